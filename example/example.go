@@ -37,7 +37,7 @@ func (h Harness) OrbitalSmoke(ctx context.Context, o *orbital.O) {
 	assert.NoError(o, err, "error marking sent")
 	// Cleanup after we're done.
 	defer h.Waiter.Delete(evt.ID)
-	assert.NoError(o, send(h.API, evt), "sending event shouldn't fail")
+	assert.NoError(o, send(o.HTTPClient(), h.API, evt), "sending event shouldn't fail")
 	// Block until the event has been received
 	r, err := h.Waiter.Wait(ctx, evt.ID)
 	assert.NoError(o, err, "error waiting")
@@ -47,14 +47,14 @@ func (h Harness) OrbitalSmoke(ctx context.Context, o *orbital.O) {
 	assert.True(o, recv.Processed, "processed should be set to true")
 }
 
-func send(api string, e event) error {
+func send(c *http.Client, api string, e event) error {
 	bs := bytes.NewBuffer(nil)
 	enc := json.NewEncoder(bs)
 	err := enc.Encode(e)
 	if err != nil {
 		return err
 	}
-	resp, err := http.Post(api, "application/json", bs)
+	resp, err := c.Post(api, "application/json", bs)
 	if err != nil {
 		return err
 	}
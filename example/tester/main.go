@@ -62,18 +62,25 @@ func main() {
 		API:    "http://localhost:3000/",
 		Waiter: rl,
 	}
-	orb.Register(orbital.TestCase{
-		Name:    "smoke test",
+	// Registers eh.OrbitalSmoke as "smoke"
+	err := orb.RegisterHarness(eh, orbital.TestCase{
 		Period:  1 * time.Second,
 		Timeout: 3 * time.Second,
-		Func:    eh.OrbitalSmoke,
 	})
-	orb.Run()
+	if err != nil {
+		events.Log("registering harness: %{error}v", err)
+		os.Exit(1)
+	}
+	if err := orb.Run(); err != nil {
+		events.Log("invalid test configuration: %{error}v", err)
+		os.Exit(1)
+	}
 
 	mux := http.NewServeMux()
 	chain := alice.New(httpstats.NewHandler, httpevents.NewHandler)
 	mux.HandleFunc("/internal/health", health)
 	mux.Handle("/internal/metrics", prometheus.DefaultHandler)
+	mux.Handle("/internal/orbital", orb)
 	mux.Handle("/", chain.Then(wh))
 
 	server := &http.Server{Addr: config.Address, Handler: mux}
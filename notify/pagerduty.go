@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/orbital/orbital"
+)
+
+// PagerDutyURL is the PagerDuty Events API v2 endpoint.
+const PagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// SeverityKey is the TestCase.Metadata key read for the PagerDuty severity.
+// It must be one of critical, error, warning or info; anything else is sent
+// as error.
+const SeverityKey = "severity"
+
+// PagerDutyConfig configures a PagerDuty notifier.
+type PagerDutyConfig struct {
+	// RoutingKey is the integration key of the PagerDuty service.
+	RoutingKey string
+	// URL defaults to PagerDutyURL.
+	URL string
+	// Source is reported as the payload source.  Defaults to "orbital".
+	Source string
+	// RunURL, if set, is a format string given the run ID to produce a link
+	// to the run, e.g. "https://status.example.com/runs/%s".
+	RunURL  string
+	Retries int
+	// Timeout bounds each delivery attempt.  Defaults to 10 seconds.
+	Timeout time.Duration
+	Client  *http.Client
+}
+
+// PagerDuty is a Notifier which triggers a PagerDuty incident when a
+// TestCase starts failing, and resolves it when the TestCase recovers.
+// Other transitions are ignored.
+type PagerDuty struct {
+	c PagerDutyConfig
+}
+
+var _ orbital.Notifier = (*PagerDuty)(nil)
+
+// NewPagerDuty returns a PagerDuty notifier for c.
+func NewPagerDuty(c PagerDutyConfig) (*PagerDuty, error) {
+	if c.RoutingKey == "" {
+		return nil, errors.New("pagerduty routing key is required")
+	}
+	if c.URL == "" {
+		c.URL = PagerDutyURL
+	}
+	if c.Source == "" {
+		c.Source = "orbital"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.Client == nil {
+		c.Client = http.DefaultClient
+	}
+	return &PagerDuty{c: c}, nil
+}
+
+type pdEvent struct {
+	RoutingKey  string     `json:"routing_key"`
+	EventAction string     `json:"event_action"`
+	DedupKey    string     `json:"dedup_key"`
+	Payload     *pdPayload `json:"payload,omitempty"`
+	Links       []pdLink   `json:"links,omitempty"`
+}
+
+type pdPayload struct {
+	Summary       string    `json:"summary"`
+	Source        string    `json:"source"`
+	Severity      string    `json:"severity"`
+	Timestamp     time.Time `json:"timestamp"`
+	CustomDetails Payload   `json:"custom_details"`
+}
+
+type pdLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// Notify sends a trigger event for StatusFailing and a resolve event for
+// StatusRecovered.  Other statuses, such as StatusBudgetBurn, are ignored.
+func (p *PagerDuty) Notify(ctx context.Context, a orbital.Alert) error {
+	ev := pdEvent{
+		RoutingKey: p.c.RoutingKey,
+		DedupKey:   DedupKey(a.Case),
+	}
+	switch a.Status {
+	case orbital.StatusFailing:
+		ev.EventAction = "trigger"
+		details := NewPayload(a)
+		ev.Payload = &pdPayload{
+			Summary:       summary(a),
+			Source:        p.c.Source,
+			Severity:      severity(a.Case),
+			Timestamp:     a.Result.Start,
+			CustomDetails: details,
+		}
+		if p.c.RunURL != "" {
+			ev.Links = []pdLink{{
+				Href: fmt.Sprintf(p.c.RunURL, a.Result.ID),
+				Text: "failed run",
+			}}
+		}
+	case orbital.StatusRecovered:
+		ev.EventAction = "resolve"
+	default:
+		return nil
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return post(ctx, p.c.Client, p.c.URL, "application/json", body, nil, p.c.Retries, p.c.Timeout)
+}
+
+// DedupKey derives the PagerDuty dedup_key for tc from its name and tags, so
+// that the trigger and resolve events of a TestCase refer to the same
+// incident.
+func DedupKey(tc orbital.TestCase) string {
+	tags := make([]string, len(tc.Tags))
+	for i, t := range tc.Tags {
+		tags[i] = t.Name + "=" + t.Value
+	}
+	sort.Strings(tags)
+	key := "orbital/" + tc.Name
+	if len(tags) > 0 {
+		key += "?" + strings.Join(tags, "&")
+	}
+	// PagerDuty limits dedup keys to 255 characters
+	if len(key) > 255 {
+		sum := sha256.Sum256([]byte(key))
+		key = "orbital/" + hex.EncodeToString(sum[:])
+	}
+	return key
+}
+
+func severity(tc orbital.TestCase) string {
+	switch s := tc.Metadata[SeverityKey]; s {
+	case "critical", "error", "warning", "info":
+		return s
+	}
+	return "error"
+}
+
+func summary(a orbital.Alert) string {
+	s := fmt.Sprintf("%s failing", a.Case.Name)
+	if errs := a.LastFailure.Errors; len(errs) > 0 {
+		s += ": " + errs[len(errs)-1]
+	}
+	// PagerDuty truncates summaries over 1024 characters.  Cut at 1024
+	// bytes, backing off to the start of a rune so the result stays valid
+	// UTF-8.
+	if len(s) > 1024 {
+		n := 1024
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		s = s[:n]
+	}
+	return s
+}
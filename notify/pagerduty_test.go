@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/segmentio/orbital/orbital"
+	"github.com/segmentio/orbital/webhook"
+	"github.com/segmentio/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestPagerDuty(t *testing.T) {
+	rc := make(chan webhook.Request, 2)
+	wh := webhook.New(webhook.Config{Logger: webhook.NewChanLogger(rc)})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wh.ServeHTTP(w, r)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	pd, err := NewPagerDuty(PagerDutyConfig{RoutingKey: "key", URL: srv.URL})
+	require.NoError(t, err)
+
+	a := testAlert
+	a.Case.Metadata = map[string]string{SeverityKey: "critical"}
+	require.NoError(t, pd.Notify(context.Background(), a))
+	a.Status = orbital.StatusRecovered
+	require.NoError(t, pd.Notify(context.Background(), a))
+
+	trigger, resolve := (<-rc).Body, (<-rc).Body
+	assert.Equal(t, "trigger", gjson.Get(trigger, "event_action").Str)
+	assert.Equal(t, "critical", gjson.Get(trigger, "payload.severity").Str)
+	assert.Equal(t, "smoke failing: event never arrived", gjson.Get(trigger, "payload.summary").Str)
+	assert.Equal(t, "resolve", gjson.Get(resolve, "event_action").Str)
+	assert.False(t, gjson.Get(resolve, "payload").Exists())
+	assert.Equal(t, gjson.Get(trigger, "dedup_key").Str, gjson.Get(resolve, "dedup_key").Str)
+}
+
+func TestDedupKey(t *testing.T) {
+	a := orbital.TestCase{Name: "smoke", Tags: []stats.Tag{stats.T("b", "2"), stats.T("a", "1")}}
+	b := orbital.TestCase{Name: "smoke", Tags: []stats.Tag{stats.T("a", "1"), stats.T("b", "2")}}
+	c := orbital.TestCase{Name: "smoke", Tags: []stats.Tag{stats.T("a", "2"), stats.T("b", "2")}}
+	assert.Equal(t, "orbital/smoke?a=1&b=2", DedupKey(a))
+	assert.Equal(t, DedupKey(a), DedupKey(b))
+	assert.NotEqual(t, DedupKey(a), DedupKey(c))
+}
+
+func TestSummaryTruncation(t *testing.T) {
+	// "é" is two bytes and follows the 15 bytes of "smoke failing: ", so
+	// byte 1024 falls inside a rune
+	msg := strings.Repeat("é", 1000)
+	s := summary(orbital.Alert{
+		Case:        orbital.TestCase{Name: "smoke"},
+		LastFailure: orbital.Result{Errors: []string{msg}},
+	})
+	assert.True(t, utf8.ValidString(s))
+	assert.Equal(t, 1023, len(s))
+}
@@ -0,0 +1,193 @@
+// Package notify provides orbital.Notifier implementations for delivering
+// alert transitions to other systems.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/orbital/orbital"
+	"github.com/segmentio/orbital/webhook"
+)
+
+// Payload is the data available to a Webhook template.  When no template is
+// configured, it is sent as JSON.
+type Payload struct {
+	Name     string            `json:"name"`
+	Tags     map[string]string `json:"tags"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Status   string            `json:"status"`
+	// Failures holds the error messages of the last failed run.
+	Failures []string  `json:"failures,omitempty"`
+	Output   string    `json:"output,omitempty"`
+	RunID    string    `json:"run_id"`
+	RunURL   string    `json:"run_url,omitempty"`
+	TraceID  string    `json:"trace_id,omitempty"`
+	Time     time.Time `json:"time"`
+	// SLO is set on budget_burn alerts.
+	SLO *orbital.SLOStatus `json:"slo,omitempty"`
+}
+
+// WebhookConfig configures a Webhook.
+type WebhookConfig struct {
+	// URL the payload is POSTed to.
+	URL string
+	// Template is a text/template rendered with a Payload to produce the
+	// request body.  The "json" function encodes its argument as JSON, which
+	// makes writing JSON templates safe, e.g. {"text": {{json .Name}}}.  If
+	// empty, the Payload is sent as JSON.
+	Template string
+	// ContentType defaults to application/json.
+	ContentType string
+	// RunURL, if set, is a format string given the run ID to produce a link
+	// to the run, e.g. "https://status.example.com/runs/%s".
+	RunURL string
+	// Secret, if set, is used to sign the body.  See webhook.Sign.
+	Secret []byte
+	// Retries is the number of times a failed delivery is retried.
+	Retries int
+	// Timeout bounds each delivery attempt.  Defaults to 10 seconds.
+	Timeout time.Duration
+	Client  *http.Client
+}
+
+// Webhook is a Notifier which POSTs alert transitions to a URL.
+type Webhook struct {
+	c    WebhookConfig
+	tmpl *template.Template
+}
+
+var _ orbital.Notifier = (*Webhook)(nil)
+
+// NewWebhook returns a Webhook for c.  An error is returned if the template
+// cannot be parsed.
+func NewWebhook(c WebhookConfig) (*Webhook, error) {
+	if c.URL == "" {
+		return nil, errors.New("webhook URL is required")
+	}
+	if c.ContentType == "" {
+		c.ContentType = "application/json"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.Client == nil {
+		c.Client = http.DefaultClient
+	}
+	w := &Webhook{c: c}
+	if c.Template != "" {
+		t, err := template.New("webhook").Funcs(template.FuncMap{
+			"json": toJSON,
+		}).Parse(c.Template)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing webhook template")
+		}
+		w.tmpl = t
+	}
+	return w, nil
+}
+
+// Notify renders the payload for a and delivers it, retrying on network
+// errors and 5xx responses.
+func (w *Webhook) Notify(ctx context.Context, a orbital.Alert) error {
+	body, err := w.render(a)
+	if err != nil {
+		return err
+	}
+	return post(ctx, w.c.Client, w.c.URL, w.c.ContentType, body, w.c.Secret, w.c.Retries, w.c.Timeout)
+}
+
+func (w *Webhook) render(a orbital.Alert) ([]byte, error) {
+	p := NewPayload(a)
+	if w.c.RunURL != "" {
+		p.RunURL = fmt.Sprintf(w.c.RunURL, p.RunID)
+	}
+	if w.tmpl == nil {
+		return json.Marshal(p)
+	}
+	var b bytes.Buffer
+	if err := w.tmpl.Execute(&b, p); err != nil {
+		return nil, errors.Wrap(err, "rendering webhook template")
+	}
+	return b.Bytes(), nil
+}
+
+// NewPayload builds the Payload describing a.
+func NewPayload(a orbital.Alert) Payload {
+	tags := make(map[string]string, len(a.Case.Tags))
+	for _, t := range a.Case.Tags {
+		tags[t.Name] = t.Value
+	}
+	return Payload{
+		Name:     a.Case.Name,
+		Tags:     tags,
+		Metadata: a.Case.Metadata,
+		Status:   a.Status.String(),
+		Failures: a.LastFailure.Errors,
+		Output:   a.LastFailure.Output,
+		RunID:    a.Result.ID,
+		TraceID:  a.Result.TraceID,
+		Time:     a.Result.Start,
+		SLO:      a.SLO,
+	}
+}
+
+func toJSON(v interface{}) (string, error) {
+	bs, err := json.Marshal(v)
+	return string(bs), err
+}
+
+// post delivers body to url, retrying up to retries times with exponential
+// backoff.
+func post(ctx context.Context, client *http.Client, url, contentType string, body, secret []byte, retries int, timeout time.Duration) error {
+	backoff := 100 * time.Millisecond
+	var err error
+	for i := 0; i <= retries; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				return errors.Wrapf(ctx.Err(), "last error: %v", err)
+			}
+		}
+		var retry bool
+		retry, err = postOnce(ctx, client, url, contentType, body, secret, timeout)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return errors.Wrapf(err, "giving up after %d attempts", retries+1)
+}
+
+func postOnce(ctx context.Context, client *http.Client, url, contentType string, body, secret []byte, timeout time.Duration) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", contentType)
+	if secret != nil {
+		req.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, body))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusTooManyRequests:
+		return true, errors.Errorf("%s: unexpected status %s", url, resp.Status)
+	case resp.StatusCode >= 300:
+		return false, errors.Errorf("%s: unexpected status %s", url, resp.Status)
+	}
+	return false, nil
+}
@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/segmentio/orbital/orbital"
+	"github.com/segmentio/orbital/webhook"
+	"github.com/segmentio/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testAlert = orbital.Alert{
+	Status: orbital.StatusFailing,
+	Case: orbital.TestCase{
+		Name: "smoke",
+		Tags: []stats.Tag{stats.T("region", "us-west-2")},
+	},
+	Result: orbital.Result{ID: "run1"},
+	LastFailure: orbital.Result{
+		ID:     "run1",
+		Failed: true,
+		Errors: []string{"event never arrived"},
+	},
+}
+
+func TestWebhook(t *testing.T) {
+	rc := make(chan webhook.Request, 1)
+	secret := []byte("shh")
+	srv := httptest.NewServer(webhook.New(webhook.Config{
+		Logger: webhook.NewChanLogger(rc),
+	}, webhook.WithSecret(secret)))
+	defer srv.Close()
+
+	w, err := NewWebhook(WebhookConfig{
+		URL:      srv.URL,
+		Template: `{"text": {{json (printf "%s is %s: %s" .Name .Status .RunURL)}}, "region": {{json .Tags.region}}, "failures": {{json .Failures}}}`,
+		RunURL:   "https://status.example.com/runs/%s",
+		Secret:   secret,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Notify(context.Background(), testAlert))
+
+	r := <-rc
+	assert.JSONEq(t, `{
+		"text": "smoke is failing: https://status.example.com/runs/run1",
+		"region": "us-west-2",
+		"failures": ["event never arrived"]
+	}`, r.Body)
+}
+
+func TestWebhookBadSecret(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		webhook.New(webhook.Config{}, webhook.WithSecret([]byte("right"))).ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	w, err := NewWebhook(WebhookConfig{URL: srv.URL, Secret: []byte("wrong"), Retries: 3})
+	require.NoError(t, err)
+	assert.Error(t, w.Notify(context.Background(), testAlert))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "4xx responses should not be retried")
+}
+
+func TestWebhookRetries(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	w, err := NewWebhook(WebhookConfig{URL: srv.URL, Retries: 2, Timeout: time.Second})
+	require.NoError(t, err)
+	require.NoError(t, w.Notify(context.Background(), testAlert))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestWebhookTemplateError(t *testing.T) {
+	_, err := NewWebhook(WebhookConfig{URL: "http://localhost", Template: "{{.Name"})
+	assert.Error(t, err)
+}
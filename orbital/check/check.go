@@ -0,0 +1,336 @@
+// Package check provides assertions for orbital tests.  Unlike testify's
+// assert used with O as its TestingT, a failed check is recorded as a
+// structured orbital.Failure in the Result of the run, with the expected and
+// actual values, a diff, the caller and the step in progress.
+//
+// Every check returns whether it passed.  The functions of the
+// orbital/check/require package perform the same checks, but stop the run
+// with O.FailNow when one fails.
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/segmentio/orbital/orbital"
+)
+
+// Equal checks that actual equals expected, as reflect.DeepEqual, except
+// that []byte values are compared with bytes.Equal.
+func Equal(o *orbital.O, expected, actual interface{}, msgAndArgs ...interface{}) bool {
+	if equal(expected, actual) {
+		return true
+	}
+	fail(o, "Equal", msgAndArgs, compare(expected, actual))
+	return false
+}
+
+// NotEqual checks that actual does not equal expected.
+func NotEqual(o *orbital.O, expected, actual interface{}, msgAndArgs ...interface{}) bool {
+	if !equal(expected, actual) {
+		return true
+	}
+	fail(o, "NotEqual", msgAndArgs, orbital.Failure{Actual: format(actual)})
+	return false
+}
+
+// True checks that value is true.
+func True(o *orbital.O, value bool, msgAndArgs ...interface{}) bool {
+	if value {
+		return true
+	}
+	fail(o, "True", msgAndArgs, orbital.Failure{Expected: "true", Actual: "false"})
+	return false
+}
+
+// False checks that value is false.
+func False(o *orbital.O, value bool, msgAndArgs ...interface{}) bool {
+	if !value {
+		return true
+	}
+	fail(o, "False", msgAndArgs, orbital.Failure{Expected: "false", Actual: "true"})
+	return false
+}
+
+// Nil checks that value is nil, or a nil pointer, map, slice, chan, func or
+// interface.
+func Nil(o *orbital.O, value interface{}, msgAndArgs ...interface{}) bool {
+	if isNil(value) {
+		return true
+	}
+	fail(o, "Nil", msgAndArgs, orbital.Failure{Expected: "nil", Actual: format(value)})
+	return false
+}
+
+// NotNil checks that value is not nil.
+func NotNil(o *orbital.O, value interface{}, msgAndArgs ...interface{}) bool {
+	if !isNil(value) {
+		return true
+	}
+	fail(o, "NotNil", msgAndArgs, orbital.Failure{Actual: "nil"})
+	return false
+}
+
+// NoError checks that err is nil.
+func NoError(o *orbital.O, err error, msgAndArgs ...interface{}) bool {
+	if err == nil {
+		return true
+	}
+	fail(o, "NoError", msgAndArgs, orbital.Failure{Actual: err.Error()})
+	return false
+}
+
+// Error checks that err is not nil.
+func Error(o *orbital.O, err error, msgAndArgs ...interface{}) bool {
+	if err != nil {
+		return true
+	}
+	fail(o, "Error", msgAndArgs, orbital.Failure{Expected: "an error", Actual: "nil"})
+	return false
+}
+
+// Contains checks that container contains elem: a substring of a string, an
+// element of a slice or array, or a key of a map.
+func Contains(o *orbital.O, container, elem interface{}, msgAndArgs ...interface{}) bool {
+	ok, found := contains(container, elem)
+	if ok && found {
+		return true
+	}
+	f := orbital.Failure{Expected: "to contain " + format(elem), Actual: format(container)}
+	if !ok {
+		f.Expected = fmt.Sprintf("a string, slice, array or map, got %T", container)
+	}
+	fail(o, "Contains", msgAndArgs, f)
+	return false
+}
+
+// NotContains checks that container does not contain elem.
+func NotContains(o *orbital.O, container, elem interface{}, msgAndArgs ...interface{}) bool {
+	ok, found := contains(container, elem)
+	if ok && !found {
+		return true
+	}
+	f := orbital.Failure{Expected: "not to contain " + format(elem), Actual: format(container)}
+	if !ok {
+		f.Expected = fmt.Sprintf("a string, slice, array or map, got %T", container)
+	}
+	fail(o, "NotContains", msgAndArgs, f)
+	return false
+}
+
+// Len checks that value, a string, slice, array, map or chan, has length n.
+func Len(o *orbital.O, value interface{}, n int, msgAndArgs ...interface{}) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		if v.Len() == n {
+			return true
+		}
+		fail(o, "Len", msgAndArgs, orbital.Failure{
+			Expected: fmt.Sprintf("length %d", n),
+			Actual:   fmt.Sprintf("length %d: %s", v.Len(), format(value)),
+		})
+	default:
+		fail(o, "Len", msgAndArgs, orbital.Failure{
+			Expected: fmt.Sprintf("length %d", n),
+			Actual:   fmt.Sprintf("%T has no length", value),
+		})
+	}
+	return false
+}
+
+// JSONEq checks that the JSON documents expected and actual are equivalent,
+// ignoring formatting and the order of object keys.
+func JSONEq(o *orbital.O, expected, actual string, msgAndArgs ...interface{}) bool {
+	var e, a interface{}
+	if err := json.Unmarshal([]byte(expected), &e); err != nil {
+		fail(o, "JSONEq", msgAndArgs, orbital.Failure{Expected: "valid JSON: " + err.Error() + "\n" + expected})
+		return false
+	}
+	if err := json.Unmarshal([]byte(actual), &a); err != nil {
+		fail(o, "JSONEq", msgAndArgs, orbital.Failure{Actual: "invalid JSON: " + err.Error() + "\n" + actual})
+		return false
+	}
+	if reflect.DeepEqual(e, a) {
+		return true
+	}
+	// Re-encode both with sorted keys and indentation to diff them
+	eb, _ := json.MarshalIndent(e, "", "  ")
+	ab, _ := json.MarshalIndent(a, "", "  ")
+	fail(o, "JSONEq", msgAndArgs, orbital.Failure{
+		Expected: string(eb),
+		Actual:   string(ab),
+		Diff:     diff(string(eb), string(ab)),
+	})
+	return false
+}
+
+func equal(expected, actual interface{}) bool {
+	if expected == nil || actual == nil {
+		return expected == actual
+	}
+	eb, ok := expected.([]byte)
+	if !ok {
+		return reflect.DeepEqual(expected, actual)
+	}
+	ab, ok := actual.([]byte)
+	if !ok {
+		return false
+	}
+	return bytes.Equal(eb, ab)
+}
+
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice, reflect.UnsafePointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// contains reports whether container is of a kind which can contain
+// elements, and if so whether it contains elem.
+func contains(container, elem interface{}) (ok, found bool) {
+	v := reflect.ValueOf(container)
+	switch v.Kind() {
+	case reflect.String:
+		e, isString := elem.(string)
+		return isString, isString && strings.Contains(v.String(), e)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if equal(v.Index(i).Interface(), elem) {
+				return true, true
+			}
+		}
+		return true, false
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			if equal(k.Interface(), elem) {
+				return true, true
+			}
+		}
+		return true, false
+	}
+	return false, false
+}
+
+var spewConfig = spew.ConfigState{
+	Indent:                  "  ",
+	DisablePointerAddresses: true,
+	DisableCapacities:       true,
+	SortKeys:                true,
+}
+
+// format formats v for a Failure: strings and errors as they are, simple
+// values with %#v and anything else as a spew dump.
+func format(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case []byte:
+		return string(v)
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprintf("%#v", v)
+	}
+	return strings.TrimSuffix(spewConfig.Sdump(v), "\n")
+}
+
+// compare returns a Failure describing the difference between expected and
+// actual.
+func compare(expected, actual interface{}) orbital.Failure {
+	f := orbital.Failure{Expected: format(expected), Actual: format(actual)}
+	if reflect.TypeOf(expected) != reflect.TypeOf(actual) {
+		f.Expected = fmt.Sprintf("%s (%T)", f.Expected, expected)
+		f.Actual = fmt.Sprintf("%s (%T)", f.Actual, actual)
+		return f
+	}
+	f.Diff = diff(f.Expected, f.Actual)
+	return f
+}
+
+// diff returns a unified diff from expected to actual, or "" if both are on
+// a single line, where a diff adds nothing.
+func diff(expected, actual string) string {
+	if !strings.Contains(expected, "\n") && !strings.Contains(actual, "\n") {
+		return ""
+	}
+	d, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(expected + "\n"),
+		B:        difflib.SplitLines(actual + "\n"),
+		FromFile: "expected",
+		ToFile:   "actual",
+		Context:  1,
+	})
+	return d
+}
+
+// fail records f as a failure of check, with the message and caller.
+func fail(o *orbital.O, check string, msgAndArgs []interface{}, f orbital.Failure) {
+	f.Check = check
+	f.Message = message(msgAndArgs)
+	f.Caller = caller()
+	o.AddFailure(f)
+}
+
+// message formats testify style message arguments: a single value, or a
+// format string followed by its arguments.
+func message(msgAndArgs []interface{}) string {
+	switch len(msgAndArgs) {
+	case 0:
+		return ""
+	case 1:
+		if s, ok := msgAndArgs[0].(string); ok {
+			return s
+		}
+		return fmt.Sprintf("%+v", msgAndArgs[0])
+	}
+	if f, ok := msgAndArgs[0].(string); ok {
+		return fmt.Sprintf(f, msgAndArgs[1:]...)
+	}
+	return fmt.Sprint(msgAndArgs...)
+}
+
+// dir is the directory of this package, whose frames caller skips along
+// with those of the require package below it.
+var dir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// caller returns the file:line of the first frame outside this package and
+// the require package.
+func caller() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		fr, more := frames.Next()
+		d := filepath.Dir(fr.File)
+		inCheck := d == dir || d == filepath.Join(dir, "require")
+		if !inCheck || strings.HasSuffix(fr.File, "_test.go") {
+			return filepath.Base(fr.File) + ":" + fmt.Sprint(fr.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/orbital/orbital"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/require"
+)
+
+// run runs fn once as a TestCase and returns its Result.
+func run(t *testing.T, fn orbital.TestFunc) orbital.Result {
+	s := orbital.New(orbital.WithOutput(ioutil.Discard))
+	defer s.Close()
+	testify.NoError(t, s.Add(orbital.TestCase{Name: "check", Period: time.Hour, Func: fn}))
+	r, err := s.RunOnce(context.Background(), "check", nil)
+	testify.NoError(t, err)
+	return r
+}
+
+type point struct {
+	X, Y int
+}
+
+func TestChecks(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(o *orbital.O) bool
+		pass bool
+	}{
+		{"Equal", func(o *orbital.O) bool { return Equal(o, point{1, 2}, point{1, 2}) }, true},
+		{"Equal bytes", func(o *orbital.O) bool { return Equal(o, []byte("a"), []byte("a")) }, true},
+		{"Equal fails", func(o *orbital.O) bool { return Equal(o, point{1, 2}, point{1, 3}) }, false},
+		{"Equal types", func(o *orbital.O) bool { return Equal(o, 1, int64(1)) }, false},
+		{"NotEqual", func(o *orbital.O) bool { return NotEqual(o, 1, 2) }, true},
+		{"NotEqual fails", func(o *orbital.O) bool { return NotEqual(o, "a", "a") }, false},
+		{"True", func(o *orbital.O) bool { return True(o, true) }, true},
+		{"True fails", func(o *orbital.O) bool { return True(o, false) }, false},
+		{"False", func(o *orbital.O) bool { return False(o, false) }, true},
+		{"Nil", func(o *orbital.O) bool { return Nil(o, (*point)(nil)) }, true},
+		{"Nil fails", func(o *orbital.O) bool { return Nil(o, &point{}) }, false},
+		{"NotNil", func(o *orbital.O) bool { return NotNil(o, &point{}) }, true},
+		{"NotNil fails", func(o *orbital.O) bool { return NotNil(o, nil) }, false},
+		{"NoError", func(o *orbital.O) bool { return NoError(o, nil) }, true},
+		{"NoError fails", func(o *orbital.O) bool { return NoError(o, errors.New("boom")) }, false},
+		{"Error", func(o *orbital.O) bool { return Error(o, errors.New("boom")) }, true},
+		{"Error fails", func(o *orbital.O) bool { return Error(o, nil) }, false},
+		{"Contains string", func(o *orbital.O) bool { return Contains(o, "hello world", "wor") }, true},
+		{"Contains slice", func(o *orbital.O) bool { return Contains(o, []int{1, 2}, 2) }, true},
+		{"Contains map", func(o *orbital.O) bool { return Contains(o, map[string]int{"a": 1}, "a") }, true},
+		{"Contains fails", func(o *orbital.O) bool { return Contains(o, []string{"a"}, "b") }, false},
+		{"Contains bad container", func(o *orbital.O) bool { return Contains(o, 1, 1) }, false},
+		{"NotContains", func(o *orbital.O) bool { return NotContains(o, "abc", "d") }, true},
+		{"NotContains fails", func(o *orbital.O) bool { return NotContains(o, "abc", "b") }, false},
+		{"Len", func(o *orbital.O) bool { return Len(o, []int{1, 2}, 2) }, true},
+		{"Len fails", func(o *orbital.O) bool { return Len(o, "abc", 2) }, false},
+		{"Len no length", func(o *orbital.O) bool { return Len(o, 1, 1) }, false},
+		{"JSONEq", func(o *orbital.O) bool { return JSONEq(o, `{"a":1,"b":[1,2]}`, `{"b": [1, 2], "a": 1}`) }, true},
+		{"JSONEq fails", func(o *orbital.O) bool { return JSONEq(o, `{"a":1}`, `{"a":2}`) }, false},
+		{"JSONEq invalid", func(o *orbital.O) bool { return JSONEq(o, `{"a":1}`, `{`) }, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var passed bool
+			r := run(t, func(ctx context.Context, o *orbital.O) {
+				passed = test.fn(o)
+			})
+			assert.Equal(t, test.pass, passed)
+			assert.Equal(t, !test.pass, r.Failed)
+			if test.pass {
+				assert.Empty(t, r.Failures)
+				return
+			}
+			testify.Len(t, r.Failures, 1)
+			assert.Equal(t, strings.Fields(test.name)[0], r.Failures[0].Check)
+			assert.Contains(t, r.Failures[0].Caller, "check_test.go:")
+		})
+	}
+}
+
+func TestEqualDiff(t *testing.T) {
+	r := run(t, func(ctx context.Context, o *orbital.O) {
+		o.Step("compare", func(ctx context.Context) error {
+			Equal(o, point{1, 2}, point{1, 3}, "point %d", 7)
+			return nil
+		})
+	})
+	testify.Len(t, r.Failures, 1)
+	f := r.Failures[0]
+	assert.Equal(t, "Equal", f.Check)
+	assert.Equal(t, "point 7", f.Message)
+	assert.Equal(t, "compare", f.Step)
+	assert.Contains(t, f.Expected, "Y: (int) 2")
+	assert.Contains(t, f.Actual, "Y: (int) 3")
+	assert.Contains(t, f.Diff, "--- expected\n+++ actual\n")
+	assert.Contains(t, f.Diff, "-  Y: (int) 2\n+  Y: (int) 3\n")
+	assert.Contains(t, r.Errors[0], "Equal failed at check_test.go:")
+	assert.Contains(t, r.Output, "diff:")
+}
+
+func TestJSONEqDiff(t *testing.T) {
+	r := run(t, func(ctx context.Context, o *orbital.O) {
+		JSONEq(o, `{"name":"a","id":1}`, `{"id":1,"name":"b"}`)
+	})
+	testify.Len(t, r.Failures, 1)
+	assert.Equal(t, "{\n  \"id\": 1,\n  \"name\": \"a\"\n}", r.Failures[0].Expected)
+	assert.Contains(t, r.Failures[0].Diff, "-  \"name\": \"a\"\n+  \"name\": \"b\"\n")
+}
@@ -0,0 +1,93 @@
+// Package require provides the checks of the orbital/check package, but
+// stops the run with O.FailNow when one fails.  Like FailNow, they must be
+// called from the goroutine running the TestFunc.
+package require
+
+import (
+	"github.com/segmentio/orbital/orbital"
+	"github.com/segmentio/orbital/orbital/check"
+)
+
+// Equal is check.Equal, stopping the run on failure.
+func Equal(o *orbital.O, expected, actual interface{}, msgAndArgs ...interface{}) {
+	if !check.Equal(o, expected, actual, msgAndArgs...) {
+		o.FailNow()
+	}
+}
+
+// NotEqual is check.NotEqual, stopping the run on failure.
+func NotEqual(o *orbital.O, expected, actual interface{}, msgAndArgs ...interface{}) {
+	if !check.NotEqual(o, expected, actual, msgAndArgs...) {
+		o.FailNow()
+	}
+}
+
+// True is check.True, stopping the run on failure.
+func True(o *orbital.O, value bool, msgAndArgs ...interface{}) {
+	if !check.True(o, value, msgAndArgs...) {
+		o.FailNow()
+	}
+}
+
+// False is check.False, stopping the run on failure.
+func False(o *orbital.O, value bool, msgAndArgs ...interface{}) {
+	if !check.False(o, value, msgAndArgs...) {
+		o.FailNow()
+	}
+}
+
+// Nil is check.Nil, stopping the run on failure.
+func Nil(o *orbital.O, value interface{}, msgAndArgs ...interface{}) {
+	if !check.Nil(o, value, msgAndArgs...) {
+		o.FailNow()
+	}
+}
+
+// NotNil is check.NotNil, stopping the run on failure.
+func NotNil(o *orbital.O, value interface{}, msgAndArgs ...interface{}) {
+	if !check.NotNil(o, value, msgAndArgs...) {
+		o.FailNow()
+	}
+}
+
+// NoError is check.NoError, stopping the run on failure.
+func NoError(o *orbital.O, err error, msgAndArgs ...interface{}) {
+	if !check.NoError(o, err, msgAndArgs...) {
+		o.FailNow()
+	}
+}
+
+// Error is check.Error, stopping the run on failure.
+func Error(o *orbital.O, err error, msgAndArgs ...interface{}) {
+	if !check.Error(o, err, msgAndArgs...) {
+		o.FailNow()
+	}
+}
+
+// Contains is check.Contains, stopping the run on failure.
+func Contains(o *orbital.O, container, elem interface{}, msgAndArgs ...interface{}) {
+	if !check.Contains(o, container, elem, msgAndArgs...) {
+		o.FailNow()
+	}
+}
+
+// NotContains is check.NotContains, stopping the run on failure.
+func NotContains(o *orbital.O, container, elem interface{}, msgAndArgs ...interface{}) {
+	if !check.NotContains(o, container, elem, msgAndArgs...) {
+		o.FailNow()
+	}
+}
+
+// Len is check.Len, stopping the run on failure.
+func Len(o *orbital.O, value interface{}, n int, msgAndArgs ...interface{}) {
+	if !check.Len(o, value, n, msgAndArgs...) {
+		o.FailNow()
+	}
+}
+
+// JSONEq is check.JSONEq, stopping the run on failure.
+func JSONEq(o *orbital.O, expected, actual string, msgAndArgs ...interface{}) {
+	if !check.JSONEq(o, expected, actual, msgAndArgs...) {
+		o.FailNow()
+	}
+}
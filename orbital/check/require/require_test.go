@@ -0,0 +1,35 @@
+package require
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/segmentio/orbital/orbital"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/require"
+)
+
+func TestRequireStopsRun(t *testing.T) {
+	s := orbital.New(orbital.WithOutput(ioutil.Discard))
+	defer s.Close()
+	after := make(chan bool, 1)
+	testify.NoError(t, s.Add(orbital.TestCase{
+		Name:   "require",
+		Period: time.Hour,
+		Func: func(ctx context.Context, o *orbital.O) {
+			Equal(o, 1, 1)
+			Contains(o, "abc", "z")
+			after <- true
+		},
+	}))
+	r, err := s.RunOnce(context.Background(), "require", nil)
+	testify.NoError(t, err)
+
+	assert.Empty(t, after, "the run should stop at the failed check")
+	assert.True(t, r.Failed)
+	testify.Len(t, r.Failures, 1)
+	assert.Equal(t, "Contains", r.Failures[0].Check)
+	assert.Contains(t, r.Failures[0].Caller, "require_test.go:")
+}
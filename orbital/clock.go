@@ -0,0 +1,267 @@
+package orbital
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TimeSource is the clock of a Service: it drives the schedule of its
+// TestCases and LoadCases, their timeouts and the abandonment of hung runs,
+// and times the runs and steps whose results alerting and SLO windows are
+// based on.  See WithClock and FakeClock.
+type TimeSource interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	NewTimer(d time.Duration) Timer
+}
+
+// Ticker is a time.Ticker created by a TimeSource.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Timer is a time.Timer created by a TimeSource.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// WithClock makes the Service use c rather than the system clock.  The
+// timeout of a run is then measured on c, so the context given to the
+// TestFunc does not report it through Deadline, which code such as a
+// net.Dialer would take as a time of the system clock; only Done and Err
+// reflect it.
+func WithClock(c TimeSource) func(*Service) {
+	return func(svc *Service) {
+		svc.clock = c
+	}
+}
+
+// SystemClock is the TimeSource of the time package.
+var SystemClock TimeSource = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+// timeSource returns the TimeSource of the Service running o.
+func (o *O) timeSource() TimeSource {
+	if o.clock == nil {
+		return SystemClock
+	}
+	return o.clock
+}
+
+type systemTicker struct{ t *time.Ticker }
+
+func (t systemTicker) C() <-chan time.Time { return t.t.C }
+func (t systemTicker) Stop()               { t.t.Stop() }
+
+type systemTimer struct{ t *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.t.C }
+func (t systemTimer) Stop() bool          { return t.t.Stop() }
+
+// FakeClock is a TimeSource for tests, whose time only moves when told to.
+// Timers and tickers fire as Advance moves the time past them.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	// closed and replaced whenever a timer or ticker is created
+	added chan struct{}
+}
+
+var _ TimeSource = (*FakeClock)(nil)
+
+// NewFakeClock returns a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, added: make(chan struct{})}
+}
+
+// Now returns the time of the clock.
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTicker returns a Ticker firing every d of fake time.
+func (f *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("orbital: non-positive interval for FakeClock.NewTicker")
+	}
+	return fakeTicker{f.add(d, d)}
+}
+
+// NewTimer returns a Timer firing after d of fake time.
+func (f *FakeClock) NewTimer(d time.Duration) Timer {
+	return f.add(d, 0)
+}
+
+func (f *FakeClock) add(d, period time.Duration) *fakeTimer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{
+		f:      f,
+		c:      make(chan time.Time, 1),
+		when:   f.now.Add(d),
+		period: period,
+	}
+	if d <= 0 {
+		t.c <- f.now
+		return t
+	}
+	f.timers = append(f.timers, t)
+	close(f.added)
+	f.added = make(chan struct{})
+	return t
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers which
+// come due in order.  Like time.Ticker, a ticker whose last tick has not been
+// received drops ticks.
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	end := f.now.Add(d)
+	for {
+		sort.Slice(f.timers, func(i, j int) bool { return f.timers[i].when.Before(f.timers[j].when) })
+		if len(f.timers) == 0 || f.timers[0].when.After(end) {
+			break
+		}
+		t := f.timers[0]
+		f.now = t.when
+		select {
+		case t.c <- t.when:
+		default:
+		}
+		if t.period > 0 {
+			t.when = t.when.Add(t.period)
+		} else {
+			f.timers = f.timers[1:]
+		}
+	}
+	f.now = end
+	f.mu.Unlock()
+}
+
+// BlockUntil waits until at least n timers and tickers are pending, which
+// lets a test Advance only once the code under test is waiting.
+func (f *FakeClock) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		pending, added := len(f.timers), f.added
+		f.mu.Unlock()
+		if pending >= n {
+			return
+		}
+		<-added
+	}
+}
+
+// Pending returns the number of timers and tickers waiting to fire.
+func (f *FakeClock) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+type fakeTimer struct {
+	f      *FakeClock
+	c      chan time.Time
+	when   time.Time
+	period time.Duration
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+// Stop stops t, and reports whether it was pending.
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	for i, ft := range t.f.timers {
+		if ft == t {
+			t.f.timers = append(t.f.timers[:i], t.f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTicker struct{ *fakeTimer }
+
+func (t fakeTicker) Stop() { t.fakeTimer.Stop() }
+
+// withTimeout is context.WithTimeout for the time of clock.
+func withTimeout(parent context.Context, clock TimeSource, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := clock.(systemClock); ok {
+		return context.WithTimeout(parent, d)
+	}
+	c := &clockCtx{
+		parent: parent,
+		done:   make(chan struct{}),
+	}
+	t := clock.NewTimer(d)
+	go func() {
+		select {
+		case <-t.C():
+			c.cancel(context.DeadlineExceeded)
+		case <-parent.Done():
+			c.cancel(parent.Err())
+		case <-c.done:
+		}
+		t.Stop()
+	}()
+	return c, func() { c.cancel(context.Canceled) }
+}
+
+// clockCtx is a context whose deadline is on a TimeSource other than the
+// system clock.
+type clockCtx struct {
+	parent context.Context
+	done   chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+func (c *clockCtx) cancel(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+		close(c.done)
+	}
+}
+
+// Deadline returns the deadline of the parent, if any, rather than its own:
+// that is a time on another clock, which code such as a net.Dialer would take
+// as a time of the system clock.
+func (c *clockCtx) Deadline() (time.Time, bool) {
+	return c.parent.Deadline()
+}
+
+func (c *clockCtx) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *clockCtx) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *clockCtx) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
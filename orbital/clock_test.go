@@ -0,0 +1,295 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var epoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func fired(c <-chan time.Time) (time.Time, bool) {
+	select {
+	case t := <-c:
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	fc := NewFakeClock(epoch)
+	timer := fc.NewTimer(time.Minute)
+	ticker := fc.NewTicker(10 * time.Second)
+	assert.Equal(t, 2, fc.Pending())
+
+	fc.Advance(59 * time.Second)
+	_, ok := fired(timer.C())
+	assert.False(t, ok)
+	// Five ticks came due, but only the first was kept
+	tick, ok := fired(ticker.C())
+	assert.True(t, ok)
+	assert.Equal(t, epoch.Add(10*time.Second), tick)
+	_, ok = fired(ticker.C())
+	assert.False(t, ok)
+
+	fc.Advance(time.Second)
+	at, ok := fired(timer.C())
+	assert.True(t, ok)
+	assert.Equal(t, epoch.Add(time.Minute), at)
+	assert.Equal(t, epoch.Add(time.Minute), fc.Now())
+	assert.False(t, timer.Stop())
+	tick, ok = fired(ticker.C())
+	assert.True(t, ok)
+	assert.Equal(t, epoch.Add(time.Minute), tick)
+
+	ticker.Stop()
+	assert.Equal(t, 0, fc.Pending())
+	fc.Advance(time.Hour)
+	_, ok = fired(ticker.C())
+	assert.False(t, ok)
+}
+
+func TestFakeClockBlockUntil(t *testing.T) {
+	fc := NewFakeClock(epoch)
+	done := make(chan struct{})
+	go func() {
+		fc.BlockUntil(2)
+		close(done)
+	}()
+	fc.NewTimer(time.Second)
+	select {
+	case <-done:
+		t.Fatal("BlockUntil returned with one timer pending")
+	default:
+	}
+	fc.NewTimer(time.Second)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("BlockUntil blocked with two timers pending")
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	fc := NewFakeClock(epoch)
+	ctx, cancel := withTimeout(context.Background(), fc, time.Minute)
+	defer cancel()
+	_, ok := ctx.Deadline()
+	assert.False(t, ok, "a deadline on the fake clock should not reach real I/O")
+
+	fc.Advance(59 * time.Second)
+	assert.NoError(t, ctx.Err())
+	fc.Advance(time.Second)
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context not done at its deadline")
+	}
+	assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+
+	ctx, cancel = withTimeout(context.Background(), fc, time.Minute)
+	cancel()
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
+
+// waitHistory waits for the scheduled runs of the named TestCase to be
+// recorded, failing the test unless exactly n are within 5 seconds.  Only
+// the real time the runs take is waited for; which runs happen is decided
+// by the FakeClock alone.
+func waitHistory(t *testing.T, s *Service, name string, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		rs, err := s.History(name, 0)
+		require.NoError(t, err)
+		if len(rs) >= n {
+			require.Len(t, rs, n, name)
+			return
+		}
+		require.True(t, time.Now().Before(deadline), "%s: %d of %d runs recorded", name, len(rs), n)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestClockSchedule(t *testing.T) {
+	fc := NewFakeClock(epoch)
+	s := New(WithClock(fc), WithOutput(ioutil.Discard))
+	runs := make(chan time.Time, 10)
+	require.NoError(t, s.Add(TestCase{
+		Name:   "every_minute",
+		Period: time.Minute,
+		Func: func(ctx context.Context, o *O) {
+			runs <- fc.Now()
+		},
+	}))
+	require.NoError(t, s.Run())
+	defer s.Close()
+
+	fc.BlockUntil(1)
+	// The ticker hasn't fired, so nothing can have run
+	fc.Advance(59 * time.Second)
+	assert.Empty(t, runs)
+	for i := 1; i <= 3; i++ {
+		fc.Advance(time.Second)
+		waitHistory(t, s, "every_minute", i)
+		assert.Equal(t, epoch.Add(time.Duration(i)*time.Minute), <-runs)
+		fc.Advance(59 * time.Second)
+		assert.Empty(t, runs)
+	}
+}
+
+func TestClockAlerts(t *testing.T) {
+	fc := NewFakeClock(epoch)
+	var alerts []Alert
+	s := New(
+		WithClock(fc),
+		WithOutput(ioutil.Discard),
+		WithAlertThresholds(3, 2),
+		WithNotifier(NotifierFunc(func(ctx context.Context, a Alert) error {
+			alerts = append(alerts, a)
+			return nil
+		})),
+	)
+	fail := true
+	require.NoError(t, s.Add(TestCase{
+		Name:   "flaky",
+		Period: time.Minute,
+		Func: func(ctx context.Context, o *O) {
+			if fail {
+				o.Error("down")
+			}
+		},
+	}))
+	require.NoError(t, s.Prepare())
+	defer s.Close()
+
+	// Four failures, then passes, a minute apart
+	for i := 1; i <= 7; i++ {
+		fc.Advance(time.Minute)
+		fail = i <= 4
+		_, err := s.RunOnce(context.Background(), "flaky", nil)
+		require.NoError(t, err)
+	}
+	require.Len(t, alerts, 2)
+	assert.Equal(t, StatusFailing, alerts[0].Status)
+	assert.Equal(t, epoch.Add(3*time.Minute), alerts[0].Result.Start)
+	assert.Equal(t, StatusRecovered, alerts[1].Status)
+	assert.Equal(t, epoch.Add(6*time.Minute), alerts[1].Result.Start)
+	assert.Equal(t, 4, alerts[1].Failures)
+}
+
+func TestClockSLOWindow(t *testing.T) {
+	fc := NewFakeClock(epoch)
+	s := New(WithClock(fc), WithOutput(ioutil.Discard), WithAlertThresholds(100, 1))
+	fail := false
+	require.NoError(t, s.Add(TestCase{
+		Name:   "smoke",
+		Period: time.Minute,
+		SLO:    &SLO{Target: 0.9, Window: time.Hour},
+		Func: func(ctx context.Context, o *O) {
+			if fail {
+				o.Error("down")
+			}
+		},
+	}))
+	require.NoError(t, s.Prepare())
+	defer s.Close()
+	run := func() {
+		_, err := s.RunOnce(context.Background(), "smoke", nil)
+		require.NoError(t, err)
+	}
+
+	// An hour of runs a minute apart, the first ten failing
+	for i := 0; i < 60; i++ {
+		fail = i < 10
+		run()
+		if i < 59 {
+			fc.Advance(time.Minute)
+		}
+	}
+	st := s.Status()[0].SLO
+	require.NotNil(t, st)
+	assert.Equal(t, int64(60), st.Runs)
+	assert.Equal(t, int64(10), st.Failures)
+	assert.InDelta(t, 50.0/60, st.Compliance, 1e-9)
+
+	// Six minutes on, the first six failures have left the window
+	fc.Advance(6 * time.Minute)
+	fail = false
+	run()
+	st = s.Status()[0].SLO
+	assert.Equal(t, int64(55), st.Runs)
+	assert.Equal(t, int64(4), st.Failures)
+	assert.InDelta(t, 0.0, st.BurnRates["5m"], 1e-9)
+}
+
+func TestClockTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	for _, tt := range []struct {
+		name string
+		fn   func(ctx context.Context, o *O)
+		dur  time.Duration
+		hung bool
+	}{
+		{
+			name: "timeout",
+			fn: func(ctx context.Context, o *O) {
+				// The run timeout is on the FakeClock, see WithClock
+				_, ok := ctx.Deadline()
+				assert.False(t, ok)
+				<-ctx.Done()
+			},
+			dur: time.Minute,
+		},
+		{
+			name: "hang",
+			fn:   func(ctx context.Context, o *O) { <-block },
+			dur:  time.Minute + 30*time.Second,
+			hung: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := NewFakeClock(epoch)
+			s := New(WithClock(fc), WithHangGrace(30*time.Second), WithOutput(ioutil.Discard))
+			require.NoError(t, s.Add(TestCase{
+				Name:    tt.name,
+				Period:  time.Hour,
+				Timeout: time.Minute,
+				Func:    tt.fn,
+			}))
+			require.NoError(t, s.Prepare())
+			defer s.Close()
+
+			results := make(chan Result, 1)
+			go func() {
+				r, err := s.RunOnce(context.Background(), tt.name, nil)
+				assert.NoError(t, err)
+				results <- r
+			}()
+			// The timeout of the run, then for a hung run the watchdog
+			// from there
+			fc.BlockUntil(1)
+			fc.Advance(time.Minute)
+			if tt.hung {
+				fc.BlockUntil(1)
+				fc.Advance(tt.dur - time.Minute)
+			}
+
+			select {
+			case r := <-results:
+				assert.True(t, r.Failed)
+				assert.Equal(t, tt.hung, r.Hung)
+				assert.Equal(t, epoch, r.Start)
+				assert.Equal(t, tt.dur, r.Duration)
+			case <-time.After(5 * time.Second):
+				t.Fatal("run not finished")
+			}
+		})
+	}
+}
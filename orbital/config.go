@@ -0,0 +1,211 @@
+package orbital
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/stats"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config overrides the scheduling of registered TestCases, keyed by
+// TestCase name.  It is usually loaded from a file given to WithConfigFile,
+// e.g.
+//
+//	tests:
+//	  smoke:
+//	    period: 30s
+//	    timeout: 10s
+//	    retries: 1
+//	    tags: {region: us-west-2}
+//	  nightly_export:
+//	    schedule: "0 3 * * *"
+//	  flaky:
+//	    enabled: false
+type Config struct {
+	Tests map[string]CaseConfig `yaml:"tests" json:"tests"`
+}
+
+// CaseConfig holds the overrides for a single TestCase.  Unset fields keep
+// the registered value.  Setting Period clears a registered Schedule and vice
+// versa.  Tags are merged into the registered tags.
+type CaseConfig struct {
+	Period   *Duration         `yaml:"period" json:"period"`
+	Schedule *string           `yaml:"schedule" json:"schedule"`
+	Timeout  *Duration         `yaml:"timeout" json:"timeout"`
+	Tags     map[string]string `yaml:"tags" json:"tags"`
+	Retries  *int              `yaml:"retries" json:"retries"`
+	Enabled  *bool             `yaml:"enabled" json:"enabled"`
+}
+
+// Duration is a time.Duration written as a string such as "1m30s" in
+// configuration files.
+type Duration time.Duration
+
+func (d *Duration) parse(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.Errorf("duration must be a string such as \"30s\", got %s", b)
+	}
+	return d.parse(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+// LoadConfig reads a Config from path.  Files ending in .json are read as
+// JSON, anything else as YAML.  Unknown fields are rejected.
+func LoadConfig(path string) (Config, error) {
+	var c Config
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, errors.Wrap(err, "reading config")
+	}
+	if filepath.Ext(path) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(bs))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&c)
+	} else {
+		err = yaml.UnmarshalStrict(bs, &c)
+	}
+	if err != nil {
+		return c, errors.Wrapf(err, "parsing config %s", path)
+	}
+	return c, nil
+}
+
+// Apply returns tests with the overrides in c applied.  An error is returned
+// if c refers to a TestCase which is not in tests, or holds an invalid value.
+func (c Config) Apply(tests []TestCase) ([]TestCase, error) {
+	known := make(map[string]bool, len(tests))
+	for _, tc := range tests {
+		known[tc.Name] = true
+	}
+	names := make([]string, 0, len(c.Tests))
+	for name := range c.Tests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !known[name] {
+			return nil, errors.Errorf("config: unknown test case %s", name)
+		}
+		if err := c.Tests[name].validate(); err != nil {
+			return nil, errors.Wrapf(err, "config: %s", name)
+		}
+	}
+
+	out := make([]TestCase, len(tests))
+	for i, tc := range tests {
+		if cc, ok := c.Tests[tc.Name]; ok {
+			tc = cc.apply(tc)
+		}
+		out[i] = tc
+	}
+	return out, nil
+}
+
+func (cc CaseConfig) validate() error {
+	if cc.Period != nil && cc.Schedule != nil {
+		return errors.New("only one of period and schedule may be set")
+	}
+	if cc.Period != nil && *cc.Period <= 0 {
+		return errors.Errorf("period must be positive, got %s", time.Duration(*cc.Period))
+	}
+	if cc.Schedule != nil {
+		if err := checkSchedule(*cc.Schedule); err != nil {
+			return err
+		}
+	}
+	if cc.Timeout != nil && *cc.Timeout < 0 {
+		return errors.Errorf("timeout must not be negative, got %s", time.Duration(*cc.Timeout))
+	}
+	if cc.Retries != nil && *cc.Retries < 0 {
+		return errors.Errorf("retries must not be negative, got %d", *cc.Retries)
+	}
+	return nil
+}
+
+func (cc CaseConfig) apply(tc TestCase) TestCase {
+	if cc.Period != nil {
+		tc.Period = time.Duration(*cc.Period)
+		tc.Schedule = ""
+	}
+	if cc.Schedule != nil {
+		tc.Schedule = *cc.Schedule
+		tc.Period = 0
+	}
+	if cc.Timeout != nil {
+		tc.Timeout = time.Duration(*cc.Timeout)
+	}
+	if cc.Retries != nil {
+		tc.Retries = *cc.Retries
+	}
+	if cc.Enabled != nil {
+		tc.Disabled = !*cc.Enabled
+	}
+	if len(cc.Tags) > 0 {
+		tc.Tags = mergeTags(tc.Tags, cc.Tags)
+	}
+	return tc
+}
+
+// mergeTags returns tags with the values in m replacing or adding to them.
+func mergeTags(tags []stats.Tag, m map[string]string) []stats.Tag {
+	out := make([]stats.Tag, 0, len(tags)+len(m))
+	seen := make(map[string]bool, len(m))
+	for _, t := range tags {
+		if v, ok := m[t.Name]; ok {
+			t.Value = v
+			seen[t.Name] = true
+		}
+		out = append(out, t)
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if !seen[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		out = append(out, stats.T(k, m[k]))
+	}
+	return out
+}
+
+// sameSchedule reports whether a and b would be run identically, so that a
+// config reload can leave the runner of an unchanged TestCase alone.
+func sameSchedule(a, b TestCase) bool {
+	if a.Period != b.Period || a.Schedule != b.Schedule || a.Timeout != b.Timeout ||
+		a.Retries != b.Retries || a.Disabled != b.Disabled || len(a.Tags) != len(b.Tags) {
+		return false
+	}
+	for i := range a.Tags {
+		if a.Tags[i] != b.Tags[i] {
+			return false
+		}
+	}
+	return true
+}
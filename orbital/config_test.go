@@ -0,0 +1,146 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, dir, name, body string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(body), 0644))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orbital")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []TestCase{
+		{Name: "smoke", Period: time.Minute, Tags: []stats.Tag{stats.T("region", "us-east-1")}},
+		{Name: "nightly", Period: time.Hour},
+		{Name: "flaky", Period: time.Minute},
+	}
+	want := []TestCase{
+		{Name: "smoke", Period: 30 * time.Second, Timeout: 10 * time.Second, Retries: 2,
+			Tags: []stats.Tag{stats.T("region", "us-west-2"), stats.T("team", "core")}},
+		{Name: "nightly", Schedule: "0 3 * * *"},
+		{Name: "flaky", Period: time.Minute, Disabled: true},
+	}
+
+	for name, body := range map[string]string{
+		"config.yml": `
+tests:
+  smoke:
+    period: 30s
+    timeout: 10s
+    retries: 2
+    tags: {region: us-west-2, team: core}
+  nightly:
+    schedule: "0 3 * * *"
+  flaky:
+    enabled: false
+`,
+		"config.json": `{"tests": {
+	"smoke": {"period": "30s", "timeout": "10s", "retries": 2, "tags": {"region": "us-west-2", "team": "core"}},
+	"nightly": {"schedule": "0 3 * * *"},
+	"flaky": {"enabled": false}
+}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, err := LoadConfig(writeConfig(t, dir, name, body))
+			require.NoError(t, err)
+			got, err := c.Apply(tests)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orbital")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []TestCase{{Name: "smoke", Period: time.Minute}}
+	for scenario, body := range map[string]string{
+		"unknown test":      "tests: {nope: {period: 1m}}",
+		"unknown field":     "tests: {smoke: {perod: 1m}}",
+		"bad duration":      "tests: {smoke: {period: soon}}",
+		"negative period":   "tests: {smoke: {period: -1m}}",
+		"zero period":       "tests: {smoke: {period: 0s}}",
+		"period and cron":   "tests: {smoke: {period: 1m, schedule: '* * * * *'}}",
+		"bad schedule":      "tests: {smoke: {schedule: 'every minute'}}",
+		"never scheduled":   "tests: {smoke: {schedule: '0 0 31 2 *'}}",
+		"negative retries":  "tests: {smoke: {retries: -1}}",
+		"negative timeout":  "tests: {smoke: {timeout: -1s}}",
+		"not a config file": "[1, 2, 3]",
+	} {
+		t.Run(scenario, func(t *testing.T) {
+			c, err := LoadConfig(writeConfig(t, dir, "config.yml", body))
+			if err == nil {
+				_, err = c.Apply(tests)
+			}
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orbital")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := writeConfig(t, dir, "config.yml", "tests: {a: {period: 1h}}")
+
+	s := New(WithConfigFile(path))
+	s.w = ioutil.Discard
+	noop := func(ctx context.Context, o *O) {}
+	s.Register(TestCase{Name: "a", Period: time.Minute, Func: noop})
+	s.Register(TestCase{Name: "b", Period: time.Minute, Func: noop})
+	require.NoError(t, s.Run())
+	defer s.Close()
+
+	a, b := s.runners["a"], s.runners["b"]
+	assert.Equal(t, time.Hour, a.tc.Period)
+
+	writeConfig(t, dir, "config.yml", "tests: {a: {period: 2h}, b: {enabled: false}}")
+	require.NoError(t, s.Reload())
+	assert.Equal(t, 2*time.Hour, s.runners["a"].tc.Period)
+	assert.True(t, s.runners["b"].tc.Disabled)
+	assert.NotEqual(t, a, s.runners["a"], "changed test case should be rescheduled")
+	assert.NotEqual(t, b, s.runners["b"], "changed test case should be rescheduled")
+
+	a = s.runners["a"]
+	writeConfig(t, dir, "config.yml", "tests: {a: {period: 2h}, b: {enabled: true}}")
+	require.NoError(t, s.Reload())
+	assert.Equal(t, a, s.runners["a"], "unchanged test case should not be rescheduled")
+
+	writeConfig(t, dir, "config.yml", "tests: {a: {period: -2h}}")
+	assert.Error(t, s.Reload())
+	assert.Equal(t, a, s.runners["a"], "invalid config should be ignored")
+}
+
+func TestReloadPrepared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orbital")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := writeConfig(t, dir, "config.yml", "tests: {a: {period: 1h}}")
+
+	s := New(WithConfigFile(path), WithOutput(ioutil.Discard))
+	require.NoError(t, s.Add(TestCase{Name: "a", Period: time.Minute, Func: func(ctx context.Context, o *O) {}}))
+	require.NoError(t, s.Prepare())
+	defer s.Close()
+	assert.Equal(t, time.Hour, s.TestCases()[0].Period)
+
+	writeConfig(t, dir, "config.yml", "tests: {a: {enabled: false}}")
+	require.NoError(t, s.Reload())
+	assert.True(t, s.TestCases()[0].Disabled, "a prepared Service should see the reloaded config")
+}
@@ -0,0 +1,135 @@
+package orbital
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/fasthash/fnv1a"
+)
+
+// Coordinator decides which of several replicas of a Service runs a
+// TestCase.  The Service consults it before every run and skips the run if
+// ShouldRun returns false or an error.
+type Coordinator interface {
+	ShouldRun(name string) (bool, error)
+}
+
+// CoordinationMode selects how a Coordinator divides work between replicas.
+type CoordinationMode int
+
+const (
+	// LeaderOnly runs every TestCase on a single replica, the leader.
+	LeaderOnly CoordinationMode = iota
+	// Sharded spreads TestCases across replicas by consistent hashing of
+	// the TestCase name.  When a replica leaves, only its TestCases move.
+	Sharded
+)
+
+// owns reports whether self should run the named TestCase among members.
+func owns(mode CoordinationMode, self string, members []string, name string) bool {
+	if len(members) == 0 {
+		return false
+	}
+	switch mode {
+	case LeaderOnly:
+		return leader(members) == self
+	case Sharded:
+		return shard(members, name) == self
+	}
+	return false
+}
+
+// leader is the lowest member ID.
+func leader(members []string) string {
+	l := members[0]
+	for _, m := range members[1:] {
+		if m < l {
+			l = m
+		}
+	}
+	return l
+}
+
+// shard picks the owner of name by rendezvous hashing.
+func shard(members []string, name string) string {
+	var owner string
+	var max uint64
+	for _, m := range members {
+		h := mix(fnv1a.AddString64(fnv1a.HashString64(m), name))
+		if owner == "" || h > max || (h == max && m < owner) {
+			owner, max = m, h
+		}
+	}
+	return owner
+}
+
+// mix is the murmur3 finalizer.  FNV alone spreads short, similar keys
+// poorly across the high bits compared here.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// MemoryGroup is a set of replicas within a single process.  It is mostly
+// useful for tests.
+type MemoryGroup struct {
+	mu      sync.Mutex
+	members map[string]struct{}
+}
+
+// NewMemoryGroup returns an empty MemoryGroup.
+func NewMemoryGroup() *MemoryGroup {
+	return &MemoryGroup{
+		members: make(map[string]struct{}),
+	}
+}
+
+// Join adds a replica identified by id to the group and returns its
+// Coordinator.  IDs must be unique within the group.
+func (g *MemoryGroup) Join(id string, mode CoordinationMode) (*MemoryCoordinator, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.members[id]; ok {
+		return nil, errors.Errorf("member %s already joined", id)
+	}
+	g.members[id] = struct{}{}
+	return &MemoryCoordinator{g: g, id: id, mode: mode}, nil
+}
+
+func (g *MemoryGroup) list() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	members := make([]string, 0, len(g.members))
+	for m := range g.members {
+		members = append(members, m)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// MemoryCoordinator is a member of a MemoryGroup.
+type MemoryCoordinator struct {
+	g    *MemoryGroup
+	id   string
+	mode CoordinationMode
+}
+
+var _ Coordinator = (*MemoryCoordinator)(nil)
+
+// ShouldRun satisfies the Coordinator interface.
+func (c *MemoryCoordinator) ShouldRun(name string) (bool, error) {
+	return owns(c.mode, c.id, c.g.list(), name), nil
+}
+
+// Close removes the replica from the group.
+func (c *MemoryCoordinator) Close() error {
+	c.g.mu.Lock()
+	delete(c.g.members, c.id)
+	c.g.mu.Unlock()
+	return nil
+}
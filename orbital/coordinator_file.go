@@ -0,0 +1,107 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package orbital
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+const memberSuffix = ".member"
+
+// FileCoordinator coordinates replicas running on a single host through lock
+// files in a shared directory.  Each replica holds an exclusive flock on its
+// own member file for as long as it is alive, so a replica which exits or
+// crashes drops out of the group without any cleanup.
+type FileCoordinator struct {
+	dir  string
+	id   string
+	mode CoordinationMode
+	f    *os.File
+}
+
+var _ Coordinator = (*FileCoordinator)(nil)
+
+// NewFileCoordinator joins the group of replicas sharing dir as id.  An error
+// is returned if another live replica is already using id.
+func NewFileCoordinator(dir, id string, mode CoordinationMode) (*FileCoordinator, error) {
+	if id == "" || strings.ContainsRune(id, filepath.Separator) {
+		return nil, errors.Errorf("invalid member id %q", id)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "creating coordinator dir")
+	}
+	f, err := os.OpenFile(filepath.Join(dir, id+memberSuffix), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening member file")
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "member %s already joined", id)
+	}
+	return &FileCoordinator{dir: dir, id: id, mode: mode, f: f}, nil
+}
+
+// ShouldRun satisfies the Coordinator interface.
+func (c *FileCoordinator) ShouldRun(name string) (bool, error) {
+	members, err := c.members()
+	if err != nil {
+		return false, err
+	}
+	return owns(c.mode, c.id, members, name), nil
+}
+
+// members lists the IDs of live replicas.  Member files which can be locked
+// belong to replicas which have gone away.
+func (c *FileCoordinator) members() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(c.dir, "*"+memberSuffix))
+	if err != nil {
+		return nil, err
+	}
+	members := []string{c.id}
+	for _, p := range paths {
+		id := strings.TrimSuffix(filepath.Base(p), memberSuffix)
+		if id == c.id {
+			continue
+		}
+		alive, err := locked(p)
+		if err != nil {
+			return nil, err
+		}
+		if alive {
+			members = append(members, id)
+		}
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+func locked(path string) (bool, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return false, nil
+}
+
+// Close leaves the group.
+func (c *FileCoordinator) Close() error {
+	os.Remove(c.f.Name())
+	return c.f.Close()
+}
@@ -0,0 +1,37 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package orbital
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCoordinator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orbital")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a, err := NewFileCoordinator(dir, "a", Sharded)
+	require.NoError(t, err)
+	defer a.Close()
+	b, err := NewFileCoordinator(dir, "b", Sharded)
+	require.NoError(t, err)
+
+	_, err = NewFileCoordinator(dir, "b", Sharded)
+	assert.Error(t, err, "a live member id should not be reused")
+
+	assert.Len(t, assertPartition(t, a, b), 2)
+
+	b.Close()
+	for _, name := range testNames {
+		ok, err := a.ShouldRun(name)
+		require.NoError(t, err)
+		assert.True(t, ok, "%s should move to a once b is gone", name)
+	}
+}
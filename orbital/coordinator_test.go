@@ -0,0 +1,78 @@
+package orbital
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNames = func() []string {
+	names := make([]string, 50)
+	for i := range names {
+		names[i] = fmt.Sprintf("case_%d", i)
+	}
+	return names
+}()
+
+// assertPartition checks that every name is run by exactly one coordinator.
+func assertPartition(t *testing.T, coords ...Coordinator) map[int]int {
+	counts := make(map[int]int)
+	for _, name := range testNames {
+		owners := 0
+		for i, c := range coords {
+			ok, err := c.ShouldRun(name)
+			require.NoError(t, err)
+			if ok {
+				owners++
+				counts[i]++
+			}
+		}
+		assert.Equal(t, 1, owners, "%s should have exactly one owner", name)
+	}
+	return counts
+}
+
+func TestMemoryCoordinator(t *testing.T) {
+	t.Run("leader", func(t *testing.T) {
+		g := NewMemoryGroup()
+		a, _ := g.Join("a", LeaderOnly)
+		b, _ := g.Join("b", LeaderOnly)
+		assert.Equal(t, map[int]int{0: len(testNames)}, assertPartition(t, a, b))
+
+		a.Close()
+		assert.Equal(t, map[int]int{1: len(testNames)}, assertPartition(t, a, b))
+	})
+
+	t.Run("sharded", func(t *testing.T) {
+		g := NewMemoryGroup()
+		a, _ := g.Join("a", Sharded)
+		b, _ := g.Join("b", Sharded)
+		c, _ := g.Join("c", Sharded)
+		counts := assertPartition(t, a, b, c)
+		assert.Len(t, counts, 3, "work should be spread over all members")
+
+		// Only c's cases should move when c leaves
+		before := make(map[string]bool)
+		for _, name := range testNames {
+			before[name], _ = a.ShouldRun(name)
+		}
+		c.Close()
+		assertPartition(t, a, b)
+		for _, name := range testNames {
+			if before[name] {
+				ok, _ := a.ShouldRun(name)
+				assert.True(t, ok, "%s should stay on a", name)
+			}
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		g := NewMemoryGroup()
+		_, err := g.Join("a", Sharded)
+		require.NoError(t, err)
+		_, err = g.Join("a", Sharded)
+		assert.Error(t, err)
+	})
+}
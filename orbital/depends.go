@@ -0,0 +1,144 @@
+package orbital
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/ksuid"
+	"github.com/segmentio/stats"
+)
+
+// dependencyNames returns the names of the TestCases among tests which the
+// dependency d refers to: the one named d or, if d is the name of a TestCase
+// registered with Params, every TestCase expanded from it.
+func dependencyNames(tests []TestCase, d string) []string {
+	var names []string
+	for _, tc := range tests {
+		if tc.Name == d {
+			return []string{d}
+		}
+		if tc.group == d {
+			names = append(names, tc.Name)
+		}
+	}
+	return names
+}
+
+// checkDependencies returns an error if any TestCase depends on an unknown
+// TestCase, or if the dependencies form a cycle.
+func checkDependencies(tests []TestCase) error {
+	deps := make(map[string][]string, len(tests))
+	for _, tc := range tests {
+		for _, d := range tc.DependsOn {
+			names := dependencyNames(tests, d)
+			if len(names) == 0 {
+				return errors.Errorf("%s depends on unknown test case %s", tc.Name, d)
+			}
+			deps[tc.Name] = append(deps[tc.Name], names...)
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(deps))
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			for i, p := range path {
+				if p == name {
+					path = append(path[i:], name)
+					break
+				}
+			}
+			return errors.Errorf("dependency cycle: %s", strings.Join(path, " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, d := range deps[name] {
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, tc := range tests {
+		if err := visit(tc.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blocked returns the reason tc should be skipped, or the empty string if it
+// should run.  A dependency which was itself skipped blocks its dependents
+// too, so a failure only skips the whole tree below it.
+func (s *Service) blocked(tc TestCase) string {
+	for _, dep := range tc.DependsOn {
+		s.mu.Lock()
+		names := dependencyNames(s.tests, dep)
+		cases := make([]*caseState, len(names))
+		for i, d := range names {
+			cases[i] = s.cases[d]
+		}
+		s.mu.Unlock()
+		for i, cs := range cases {
+			if cs == nil {
+				continue
+			}
+			last, ok := cs.lastResult()
+			switch {
+			case !ok:
+			case last.Failed:
+				return fmt.Sprintf("dependency %s failing", names[i])
+			case last.Skipped:
+				return fmt.Sprintf("dependency %s skipped", names[i])
+			}
+		}
+	}
+	return ""
+}
+
+// skipScheduled is skip for a scheduled run, which is dropped instead while
+// RunOnce runs tc.
+func (s *Service) skipScheduled(tc TestCase, reason string) {
+	s.mu.Lock()
+	cs := s.cases[tc.Name]
+	s.mu.Unlock()
+	if cs != nil {
+		if !cs.begin(false) {
+			return
+		}
+		defer cs.end(false)
+	}
+	s.skip(s.w, tc, reason)
+}
+
+// skip records a skipped run of tc.
+func (s *Service) skip(w io.Writer, tc TestCase, reason string) Result {
+	tags := append([]stats.Tag{
+		stats.T("case", tc.Name),
+	}, tc.Tags...)
+	s.stats.Incr("case.skip", tags...)
+	fmt.Fprintf(w, "--- SKIP: %s (%s)\n", tc.Name, reason)
+	r := Result{
+		ID:         ksuid.New().String(),
+		Name:       tc.Name,
+		Tags:       tc.Tags,
+		Start:      s.clock.Now(),
+		Skipped:    true,
+		SkipReason: reason,
+	}
+	s.record(tc, r)
+	return r
+}
@@ -0,0 +1,165 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDependencies(t *testing.T) {
+	tests := []struct {
+		scenario string
+		cases    []TestCase
+		err      string
+	}{
+		{
+			scenario: "no dependencies",
+			cases:    []TestCase{{Name: "a"}, {Name: "b"}},
+		},
+		{
+			scenario: "chain",
+			cases: []TestCase{
+				{Name: "a"},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c", DependsOn: []string{"a", "b"}},
+			},
+		},
+		{
+			scenario: "unknown",
+			cases:    []TestCase{{Name: "a", DependsOn: []string{"z"}}},
+			err:      "a depends on unknown test case z",
+		},
+		{
+			scenario: "params",
+			cases: []TestCase{
+				{Name: "a.us", group: "a"},
+				{Name: "a.eu", group: "a"},
+				{Name: "b", DependsOn: []string{"a", "a.eu"}},
+			},
+		},
+		{
+			scenario: "params cycle",
+			cases: []TestCase{
+				{Name: "a.us", group: "a"},
+				{Name: "a.eu", group: "a", DependsOn: []string{"b"}},
+				{Name: "b", DependsOn: []string{"a"}},
+			},
+			err: "dependency cycle: a.eu -> b -> a.eu",
+		},
+		{
+			scenario: "self",
+			cases:    []TestCase{{Name: "a", DependsOn: []string{"a"}}},
+			err:      "dependency cycle: a -> a",
+		},
+		{
+			scenario: "cycle",
+			cases: []TestCase{
+				{Name: "a", DependsOn: []string{"c"}},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c", DependsOn: []string{"b"}},
+			},
+			err: "dependency cycle: a -> c -> b -> a",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			err := checkDependencies(test.cases)
+			if test.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+		})
+	}
+}
+
+func TestRunRejectsCycles(t *testing.T) {
+	s := New()
+	s.Register(TestCase{Name: "a", DependsOn: []string{"b"}})
+	s.Register(TestCase{Name: "b", DependsOn: []string{"a"}})
+	assert.Error(t, s.Run())
+}
+
+func TestDependencySkip(t *testing.T) {
+	s := New(WithOutput(ioutil.Discard))
+	failing := true
+	api := TestCase{
+		Name:   "api",
+		Period: time.Hour,
+		Func: func(ctx context.Context, o *O) {
+			if failing {
+				o.Error("api down")
+			}
+		},
+	}
+	noop := func(ctx context.Context, o *O) {}
+	ingest := TestCase{Name: "ingest", Period: time.Hour, Func: noop, DependsOn: []string{"api"}}
+	warehouse := TestCase{Name: "warehouse", Period: time.Hour, Func: noop, DependsOn: []string{"ingest"}}
+	require.NoError(t, s.Add(ingest))
+	require.NoError(t, s.Add(warehouse))
+	runAPI := startCase(t, s, api)
+	defer s.Close()
+
+	assert.Equal(t, "", s.blocked(ingest), "dependencies which have not run yet should not block")
+
+	runAPI()
+	assert.Equal(t, "dependency api failing", s.blocked(ingest))
+	s.skip(s.w, ingest, s.blocked(ingest))
+	assert.Equal(t, "dependency ingest skipped", s.blocked(warehouse))
+
+	last, _ := s.cases["ingest"].lastResult()
+	assert.True(t, last.Skipped)
+	assert.Equal(t, "dependency api failing", last.SkipReason)
+
+	failing = false
+	runAPI()
+	assert.Equal(t, "", s.blocked(ingest))
+}
+
+func TestDependencyParams(t *testing.T) {
+	s := New(WithEnvironments(Environment{Name: "staging"}), WithOutput(ioutil.Discard))
+	failing := ""
+	require.NoError(t, s.Add(TestCase{
+		Name:   "api",
+		Period: time.Hour,
+		Params: map[string][]string{"region": {"us", "eu"}},
+		Func: func(ctx context.Context, o *O) {
+			if o.Param("region") == failing {
+				o.Error("api down")
+			}
+		},
+	}))
+	require.NoError(t, s.Add(TestCase{
+		Name:      "ingest",
+		Period:    time.Hour,
+		DependsOn: []string{"api"},
+		Func:      func(ctx context.Context, o *O) {},
+	}))
+	require.NoError(t, s.Prepare())
+	defer s.Close()
+
+	var ingest TestCase
+	for _, tc := range s.TestCases() {
+		if tc.Name == "ingest.staging" {
+			ingest = tc
+		}
+	}
+	require.Equal(t, []string{"api.staging"}, ingest.DependsOn)
+
+	for _, name := range []string{"api.us.staging", "api.eu.staging"} {
+		_, err := s.RunOnce(context.Background(), name, nil)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "", s.blocked(ingest))
+
+	failing = "eu"
+	_, err := s.RunOnce(context.Background(), "api.eu.staging", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "dependency api.eu.staging failing", s.blocked(ingest))
+}
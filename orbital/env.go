@@ -0,0 +1,86 @@
+package orbital
+
+import (
+	"github.com/pkg/errors"
+	"github.com/segmentio/stats"
+)
+
+// Environment is a target the TestCases of a Service are run against, such
+// as staging or production-eu.  Config holds what a TestFunc needs to reach
+// it, such as URLs, and is read with O.Env.  Tags are added to those of
+// every TestCase run against it.
+type Environment struct {
+	Name   string
+	Config map[string]string
+	Tags   []stats.Tag
+}
+
+// WithEnvironments makes the Service run every TestCase once per
+// Environment.  Each TestCase, after expanding its Params, is scheduled under
+// its name with the Environment name appended, e.g. smoke.staging, and
+// tagged with env=staging and the Environment Tags.  DependsOn refers to the
+// TestCases of the same Environment.
+func WithEnvironments(envs ...Environment) func(*Service) {
+	return func(svc *Service) {
+		svc.envs = envs
+	}
+}
+
+// expand returns the TestCases tc is registered as, expanding its Params and
+// the Environments of s.
+func (s *Service) expand(tc TestCase) []TestCase {
+	cases := expandParams(tc)
+	if len(s.envs) == 0 {
+		return cases
+	}
+	out := make([]TestCase, 0, len(cases)*len(s.envs))
+	for _, env := range s.envs {
+		for _, t := range cases {
+			out = append(out, withEnvironment(t, env))
+		}
+	}
+	return out
+}
+
+// withEnvironment returns tc for running against env.
+func withEnvironment(tc TestCase, env Environment) TestCase {
+	t := tc
+	t.Name = tc.Name + "." + env.Name
+	t.env = env
+	if tc.group != "" {
+		t.group = tc.group + "." + env.Name
+	}
+	tags := make([]stats.Tag, 0, len(tc.Tags)+len(env.Tags)+1)
+	tags = append(tags, tc.Tags...)
+	tags = append(tags, stats.T("env", env.Name))
+	t.Tags = append(tags, env.Tags...)
+	if len(tc.DependsOn) > 0 {
+		t.DependsOn = make([]string, len(tc.DependsOn))
+		for i, d := range tc.DependsOn {
+			t.DependsOn[i] = d + "." + env.Name
+		}
+	}
+	return t
+}
+
+// validateEnvironments checks that every Environment has a unique, non-empty
+// name.
+func validateEnvironments(envs []Environment) error {
+	seen := make(map[string]bool, len(envs))
+	for _, env := range envs {
+		if env.Name == "" {
+			return errors.New("environment name must not be empty")
+		}
+		if seen[env.Name] {
+			return errors.Errorf("environment %s configured more than once", env.Name)
+		}
+		seen[env.Name] = true
+	}
+	return nil
+}
+
+// Env returns the Environment this run of a TestCase targets, or the zero
+// Environment if the Service has none.
+func (o *O) Env() Environment {
+	return o.env
+}
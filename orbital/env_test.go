@@ -0,0 +1,115 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/segmentio/stats"
+	"github.com/segmentio/stats/statstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironments(t *testing.T) {
+	h := &statstest.Handler{}
+	s := New(
+		WithStats(stats.NewEngine("", h)),
+		WithOutput(ioutil.Discard),
+		WithEnvironments(
+			Environment{
+				Name:   "staging",
+				Config: map[string]string{"url": "https://staging.example.com"},
+			},
+			Environment{
+				Name:   "production-eu",
+				Config: map[string]string{"url": "https://eu.example.com"},
+				Tags:   []stats.Tag{stats.T("region", "eu-west-1")},
+			},
+		),
+	)
+	urls := make(chan string, 2)
+	require.NoError(t, s.Add(TestCase{
+		Name:   "ingest",
+		Period: time.Hour,
+		Tags:   []stats.Tag{stats.T("team", "core")},
+		Func: func(ctx context.Context, o *O) {
+			o.Incr("sent")
+			urls <- o.Env().Config["url"]
+		},
+	}))
+	require.NoError(t, s.Add(TestCase{
+		Name:      "query",
+		Period:    time.Hour,
+		DependsOn: []string{"ingest"},
+		Func:      func(ctx context.Context, o *O) {},
+	}))
+	assert.Error(t, s.Add(TestCase{
+		Name:   "ingest",
+		Period: time.Hour,
+		Func:   func(ctx context.Context, o *O) {},
+	}), "expanded names should not clash")
+
+	var names []string
+	for _, tc := range s.TestCases() {
+		names = append(names, tc.Name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{
+		"ingest.production-eu",
+		"ingest.staging",
+		"query.production-eu",
+		"query.staging",
+	}, names)
+
+	tcs := s.TestCases()
+	assert.Equal(t, []stats.Tag{
+		stats.T("team", "core"),
+		stats.T("env", "production-eu"),
+		stats.T("region", "eu-west-1"),
+	}, tcs[1].Tags)
+	assert.Equal(t, []string{"ingest.production-eu"}, tcs[3].DependsOn)
+	require.NoError(t, s.Validate())
+
+	r, err := s.RunOnce(context.Background(), "ingest.production-eu", nil)
+	require.NoError(t, err)
+	defer s.Close()
+	assert.False(t, r.Failed)
+	assert.Equal(t, "https://eu.example.com", <-urls)
+	assert.Equal(t, "production-eu", tagValue(r.Tags, "env"))
+	sent := measures(h, "sent")
+	require.Len(t, sent, 1)
+	assert.Equal(t, "production-eu", tagValue(sent[0].Tags, "env"))
+
+	var o O
+	assert.Equal(t, Environment{}, o.Env())
+}
+
+func TestValidateEnvironments(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		envs []Environment
+		ok   bool
+	}{
+		{name: "none", ok: true},
+		{name: "unique", envs: []Environment{{Name: "staging"}, {Name: "production"}}, ok: true},
+		{name: "empty", envs: []Environment{{Name: ""}}},
+		{name: "duplicate", envs: []Environment{{Name: "staging"}, {Name: "staging"}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(WithEnvironments(tt.envs...))
+			s.Register(TestCase{
+				Name:   "smoke",
+				Period: time.Hour,
+				Func:   func(ctx context.Context, o *O) {},
+			})
+			if tt.ok {
+				assert.NoError(t, s.Validate())
+			} else {
+				assert.Error(t, s.Validate())
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package orbital
+
+import (
+	"strings"
+)
+
+// Failure is a structured description of a failed check, as recorded by the
+// orbital/check package.
+type Failure struct {
+	// Check names the failed check, e.g. "Equal".
+	Check string `json:"check"`
+	// Message is the message given to the check, if any.
+	Message string `json:"message,omitempty"`
+	// Expected and Actual are the compared values, formatted for display.
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	// Diff is a unified diff from Expected to Actual, if they span
+	// multiple lines.
+	Diff string `json:"diff,omitempty"`
+	// Caller is the file:line the check was called from.
+	Caller string `json:"caller,omitempty"`
+	// Step is the step in progress when the check failed.
+	Step string `json:"step,omitempty"`
+}
+
+// String formats f for the output of a run.
+func (f Failure) String() string {
+	var b strings.Builder
+	b.WriteString(f.Check)
+	b.WriteString(" failed")
+	if f.Caller != "" {
+		b.WriteString(" at ")
+		b.WriteString(f.Caller)
+	}
+	if f.Message != "" {
+		b.WriteString(": ")
+		b.WriteString(f.Message)
+	}
+	if f.Expected != "" {
+		b.WriteString("\n  expected: ")
+		b.WriteString(indent(f.Expected))
+	}
+	if f.Actual != "" {
+		b.WriteString("\n  actual:   ")
+		b.WriteString(indent(f.Actual))
+	}
+	if f.Diff != "" {
+		b.WriteString("\n  diff:\n    ")
+		b.WriteString(indent(strings.TrimSuffix(f.Diff, "\n")))
+	}
+	return b.String()
+}
+
+// indent indents continuation lines to line up under a Failure field.
+func indent(s string) string {
+	return strings.Replace(s, "\n", "\n    ", -1)
+}
+
+// AddFailure fails the test, recording f in Result.Failures and logging it
+// as an error.  f.Step is set to the step in progress.
+func (o *O) AddFailure(f Failure) {
+	o.mu.Lock()
+	f.Step = o.step
+	o.failures = append(o.failures, f)
+	o.mu.Unlock()
+	o.Error(f.String())
+}
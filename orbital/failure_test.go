@@ -0,0 +1,69 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailNow(t *testing.T) {
+	s := New(WithOutput(ioutil.Discard))
+	after := false
+	deferred := false
+	run := startCase(t, s, TestCase{
+		Name:   "smoke",
+		Period: time.Hour,
+		Func: func(ctx context.Context, o *O) {
+			defer func() { deferred = true }()
+			o.Step("connect", func(ctx context.Context) error {
+				o.AddFailure(Failure{Check: "Equal", Expected: "1", Actual: "2", Caller: "x.go:1"})
+				o.FailNow()
+				return errors.New("unreachable")
+			})
+			after = true
+		},
+	})
+	defer s.Close()
+	last := run()
+
+	assert.False(t, after, "FailNow should stop the run")
+	assert.True(t, deferred, "deferred calls should run")
+	assert.True(t, last.Failed)
+	assert.False(t, last.Hung)
+	assert.Equal(t, "connect", last.FailedStep)
+	require.Len(t, last.Steps, 1)
+	assert.True(t, last.Steps[0].Failed)
+	assert.Equal(t, []Failure{{
+		Check:    "Equal",
+		Expected: "1",
+		Actual:   "2",
+		Caller:   "x.go:1",
+		Step:     "connect",
+	}}, last.Failures)
+	assert.Equal(t, []string{"Equal failed at x.go:1\n  expected: 1\n  actual:   2"}, last.Errors)
+	assert.Contains(t, last.Output, "--- STEP FAIL: connect")
+}
+
+func TestFatalInLoad(t *testing.T) {
+	s := New()
+	s.w = ioutil.Discard
+	require.NoError(t, s.AddLoad(LoadCase{
+		Name:        "load",
+		Concurrency: 1,
+		Duration:    50 * time.Millisecond,
+		Func: func(ctx context.Context, o *O) {
+			o.Fatal("stop")
+		},
+	}))
+	require.NoError(t, s.Run())
+	time.Sleep(100 * time.Millisecond)
+	s.Close()
+	st := s.LoadStatus()[0]
+	assert.True(t, st.Runs > 1, "worker should survive Fatal")
+	assert.Equal(t, st.Runs, st.Errors)
+}
@@ -0,0 +1,104 @@
+package orbital
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	snakecase "github.com/segmentio/go-snakecase"
+)
+
+// harnessPrefix marks the methods of a harness which are TestFuncs.
+const harnessPrefix = "Orbital"
+
+// HarnessConfigurer may be implemented by a harness given to
+// RegisterHarness to adjust the TestCase generated for each method, e.g. to
+// give a slow test a longer Timeout.  method is the Go method name.
+type HarnessConfigurer interface {
+	ConfigureTestCase(method string, tc *TestCase)
+}
+
+// SuiteSetup may be implemented by a harness given to RegisterHarness.
+// SetupSuite is called by Service.Run before any test is started.
+type SuiteSetup interface {
+	SetupSuite() error
+}
+
+// SuiteTeardown may be implemented by a harness given to RegisterHarness.
+// TeardownSuite is called by Service.Close once all tests have stopped.
+type SuiteTeardown interface {
+	TeardownSuite() error
+}
+
+// RegisterHarness registers every exported method of h named Orbital* with
+// the TestFunc signature as a TestCase.  Each TestCase starts as a copy of
+// defaults, named after the method with the prefix removed, in snake case as
+// by go-snakecase, e.g. OrbitalSmokeTest becomes smoke_test and
+// OrbitalHTTPCheck httpcheck.  If defaults.Name is set it is used as a
+// prefix, separated by a dot.  Each TestCase is checked as by Add, and if any
+// is invalid or clashes with another, an error is returned and neither the
+// harness nor any of its TestCases is registered.
+func (s *Service) RegisterHarness(h interface{}, defaults TestCase) error {
+	v := reflect.ValueOf(h)
+	t := v.Type()
+	fnType := reflect.TypeOf(TestFunc(nil))
+
+	var tests []TestCase
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if !strings.HasPrefix(m.Name, harnessPrefix) || len(m.Name) == len(harnessPrefix) {
+			continue
+		}
+		mv := v.Method(i)
+		if !mv.Type().ConvertibleTo(fnType) {
+			continue
+		}
+		tc := defaults
+		tc.Func = mv.Convert(fnType).Interface().(TestFunc)
+		tc.Name = snakecase.Snakecase(strings.TrimPrefix(m.Name, harnessPrefix))
+		if defaults.Name != "" {
+			tc.Name = defaults.Name + "." + tc.Name
+		}
+		if c, ok := h.(HarnessConfigurer); ok {
+			c.ConfigureTestCase(m.Name, &tc)
+		}
+		tests = append(tests, tc)
+	}
+	if len(tests) == 0 {
+		return errors.Errorf("%s has no %s methods of type func(context.Context, *orbital.O)", t, harnessPrefix)
+	}
+
+	return s.addCases(tests, h)
+}
+
+// setupSuites calls SetupSuite on each harness.  If one fails, the harnesses
+// already set up are torn down.
+func (s *Service) setupSuites() error {
+	for i, h := range s.harnesses {
+		su, ok := h.(SuiteSetup)
+		if !ok {
+			continue
+		}
+		if err := su.SetupSuite(); err != nil {
+			teardownSuites(s.harnesses[:i])
+			return errors.Wrapf(err, "setting up %T", h)
+		}
+	}
+	return nil
+}
+
+// teardownSuites calls TeardownSuite on each harness in reverse order of
+// setup, returning the first error.
+func teardownSuites(harnesses []interface{}) error {
+	var err error
+	for i := len(harnesses) - 1; i >= 0; i-- {
+		td, ok := harnesses[i].(SuiteTeardown)
+		if !ok {
+			continue
+		}
+		if e := td.TeardownSuite(); e != nil && err == nil {
+			err = errors.Wrapf(e, "tearing down %T", harnesses[i])
+		}
+	}
+	return err
+}
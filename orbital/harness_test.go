@@ -0,0 +1,97 @@
+package orbital
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testHarness struct {
+	calls    []string
+	setupErr error
+}
+
+func (h *testHarness) OrbitalSmoke(ctx context.Context, o *O)      {}
+func (h *testHarness) OrbitalHTTPCheck(ctx context.Context, o *O)  {}
+func (h *testHarness) OrbitalWrongSignature(ctx context.Context)   {}
+func (h *testHarness) Orbital(ctx context.Context, o *O)           {}
+func (h *testHarness) NotATest(ctx context.Context, o *O)          {}
+func (h *testHarness) orbitalUnexported(ctx context.Context, o *O) {}
+
+func (h *testHarness) ConfigureTestCase(method string, tc *TestCase) {
+	if method == "OrbitalHTTPCheck" {
+		tc.Timeout = time.Minute
+	}
+}
+
+func (h *testHarness) SetupSuite() error {
+	h.calls = append(h.calls, "setup")
+	return h.setupErr
+}
+
+func (h *testHarness) TeardownSuite() error {
+	h.calls = append(h.calls, "teardown")
+	return nil
+}
+
+func TestRegisterHarness(t *testing.T) {
+	h := &testHarness{}
+	s := New()
+	require.NoError(t, s.RegisterHarness(h, TestCase{
+		Name:    "suite",
+		Period:  time.Hour,
+		Timeout: time.Second,
+	}))
+
+	byName := make(map[string]TestCase)
+	var names []string
+	for _, tc := range s.tests {
+		byName[tc.Name] = tc
+		names = append(names, tc.Name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"suite.httpcheck", "suite.smoke"}, names)
+	assert.Equal(t, time.Hour, byName["suite.smoke"].Period)
+	assert.Equal(t, time.Second, byName["suite.smoke"].Timeout)
+	assert.Equal(t, time.Minute, byName["suite.httpcheck"].Timeout)
+
+	require.NoError(t, s.Run())
+	assert.Equal(t, []string{"setup"}, h.calls)
+	require.NoError(t, s.Close())
+	assert.Equal(t, []string{"setup", "teardown"}, h.calls)
+}
+
+func TestRegisterHarnessErrors(t *testing.T) {
+	s := New()
+	assert.Error(t, s.RegisterHarness(struct{}{}, TestCase{}), "harness without tests")
+
+	h := &testHarness{setupErr: errors.New("no database")}
+	require.NoError(t, s.RegisterHarness(h, TestCase{Period: time.Hour}))
+	assert.Error(t, s.Run())
+	assert.Equal(t, []string{"setup"}, h.calls, "failed setup should not be torn down")
+}
+
+type clashingHarness struct{}
+
+func (clashingHarness) OrbitalFooBar(ctx context.Context, o *O)  {}
+func (clashingHarness) OrbitalFoo_Bar(ctx context.Context, o *O) {}
+
+func TestRegisterHarnessAtomic(t *testing.T) {
+	s := New()
+	require.NoError(t, s.Add(TestCase{
+		Name:   "suite.smoke",
+		Period: time.Hour,
+		Func:   func(ctx context.Context, o *O) {},
+	}))
+	assert.Error(t, s.RegisterHarness(&testHarness{}, TestCase{Name: "suite", Period: time.Hour}))
+	assert.Error(t, s.RegisterHarness(clashingHarness{}, TestCase{Period: time.Hour}))
+	require.Len(t, s.tests, 1, "no TestCase of a rejected harness should be registered")
+	assert.Equal(t, "suite.smoke", s.tests[0].Name)
+	assert.Len(t, s.cases, 1)
+	assert.Empty(t, s.harnesses)
+}
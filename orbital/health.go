@@ -0,0 +1,52 @@
+package orbital
+
+import (
+	"sync/atomic"
+
+	"github.com/segmentio/stats"
+)
+
+// reportHealth sets the health gauges of tc, so that a TestCase which stops
+// running, for whatever reason, is still visible:
+//
+//	case.last_run              unix time the last run started, 0 if never
+//	case.last_success          unix time the last passing run started
+//	case.consecutive_failures  failed runs since the last pass
+//	case.running               runs in progress
+//	case.up                    1 if the last run passed, 0 otherwise
+//
+// It is called on every tick where this replica owns tc (see Coordinator),
+// including those where the run is skipped or the TestCase is disabled.
+func (s *Service) reportHealth(tc TestCase) {
+	s.mu.Lock()
+	cs := s.cases[tc.Name]
+	s.mu.Unlock()
+	if cs == nil {
+		return
+	}
+	cs.mu.Lock()
+	lastRun, lastSuccess := cs.lastRun, cs.lastSuccess
+	cs.mu.Unlock()
+	failures := cs.alert.consecutive()
+
+	up := 0
+	if !lastRun.IsZero() && lastRun.Equal(lastSuccess) {
+		up = 1
+	}
+	var lastRunUnix, lastSuccessUnix int64
+	if !lastRun.IsZero() {
+		lastRunUnix = lastRun.Unix()
+	}
+	if !lastSuccess.IsZero() {
+		lastSuccessUnix = lastSuccess.Unix()
+	}
+
+	tags := append([]stats.Tag{
+		stats.T("case", tc.Name),
+	}, tc.Tags...)
+	s.stats.Set("case.last_run", lastRunUnix, tags...)
+	s.stats.Set("case.last_success", lastSuccessUnix, tags...)
+	s.stats.Set("case.consecutive_failures", failures, tags...)
+	s.stats.Set("case.running", atomic.LoadInt64(&cs.running), tags...)
+	s.stats.Set("case.up", up, tags...)
+}
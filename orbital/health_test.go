@@ -0,0 +1,101 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/segmentio/stats"
+	"github.com/segmentio/stats/statstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lastGauge(h *statstest.Handler, name string) (int64, bool) {
+	ms := measures(h, name)
+	if len(ms) == 0 {
+		return 0, false
+	}
+	return ms[len(ms)-1].Fields[0].Value.Int(), true
+}
+
+func TestReportHealth(t *testing.T) {
+	h := &statstest.Handler{}
+	fail := true
+	s := New(WithStats(stats.NewEngine("", h)), WithOutput(ioutil.Discard))
+	run := startCase(t, s, TestCase{
+		Name:   "smoke",
+		Period: time.Hour,
+		Func: func(ctx context.Context, o *O) {
+			if fail {
+				o.Error("down")
+			}
+		},
+	})
+	defer s.Close()
+	tc := s.tests[0]
+
+	s.reportHealth(tc)
+	for _, name := range []string{"case.last_run", "case.last_success", "case.consecutive_failures", "case.running", "case.up"} {
+		v, ok := lastGauge(h, name)
+		require.True(t, ok, name)
+		assert.Zero(t, v, name)
+	}
+
+	run()
+	run()
+	s.reportHealth(tc)
+	v, _ := lastGauge(h, "case.consecutive_failures")
+	assert.Equal(t, int64(2), v)
+	v, _ = lastGauge(h, "case.last_run")
+	assert.InDelta(t, time.Now().Unix(), v, 2)
+	v, _ = lastGauge(h, "case.last_success")
+	assert.Zero(t, v)
+	v, _ = lastGauge(h, "case.up")
+	assert.Zero(t, v)
+
+	fail = false
+	run()
+	s.reportHealth(tc)
+	v, _ = lastGauge(h, "case.consecutive_failures")
+	assert.Zero(t, v)
+	v, _ = lastGauge(h, "case.last_success")
+	assert.InDelta(t, time.Now().Unix(), v, 2)
+	v, _ = lastGauge(h, "case.up")
+	assert.Equal(t, int64(1), v)
+	assert.Equal(t, "smoke", tagValue(measures(h, "case.up")[0].Tags, "case"))
+}
+
+func TestReportHealthDisabled(t *testing.T) {
+	h := &statstest.Handler{}
+	s := New(WithStats(stats.NewEngine("", h)), WithOutput(ioutil.Discard))
+	require.NoError(t, s.Add(TestCase{
+		Name:     "paused",
+		Period:   10 * time.Millisecond,
+		Disabled: true,
+		Func:     func(ctx context.Context, o *O) { t.Error("disabled case ran") },
+	}))
+	require.NoError(t, s.Run())
+	time.Sleep(55 * time.Millisecond)
+	s.Close()
+	assert.True(t, len(measures(h, "case.up")) >= 3)
+}
+
+type neverCoordinator struct{}
+
+func (neverCoordinator) ShouldRun(name string) (bool, error) { return false, nil }
+
+func TestReportHealthNotOwned(t *testing.T) {
+	h := &statstest.Handler{}
+	s := New(WithStats(stats.NewEngine("", h)), WithCoordinator(neverCoordinator{}), WithOutput(ioutil.Discard))
+	require.NoError(t, s.Add(TestCase{
+		Name:   "elsewhere",
+		Period: 10 * time.Millisecond,
+		Func:   func(ctx context.Context, o *O) { t.Error("case owned by another replica ran") },
+	}))
+	require.NoError(t, s.Run())
+	time.Sleep(55 * time.Millisecond)
+	s.Close()
+	assert.Empty(t, measures(h, "case.up"))
+}
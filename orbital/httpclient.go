@@ -0,0 +1,200 @@
+package orbital
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/stats"
+)
+
+// httpBodyLimit is the number of bytes of each request and response body
+// kept by the client from O.HTTPClient.
+const httpBodyLimit = 4096
+
+// redactedHeaders are not kept by the client from O.HTTPClient.
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"Set-Cookie":          true,
+}
+
+// HTTPClient returns an http.Client for use during the run.  Requests made
+// without a context of their own, such as with Get or Post, are bound to the
+// context of the run, or of the step in progress, and carry its traceparent.
+//
+// Every request is observed in the "http.request" histogram through Observe,
+// tagged with the method, host and status; the status is "error" if no
+// response was received.  The requests and responses, with their bodies
+// truncated to 4KB and credentials redacted, are logged if the run fails.
+func (o *O) HTTPClient() *http.Client {
+	return &http.Client{Transport: &runTransport{o: o, base: &TraceTransport{}}}
+}
+
+// runTransport is the http.RoundTripper of O.HTTPClient.
+type runTransport struct {
+	o    *O
+	base http.RoundTripper
+}
+
+func (t *runTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A context which can never be cancelled is one the caller didn't set
+	if req.Context().Done() == nil {
+		t.o.mu.Lock()
+		ctx := t.o.ctx
+		t.o.mu.Unlock()
+		req = req.WithContext(ctx)
+	}
+	ex := &exchange{
+		method: req.Method,
+		url:    req.URL.String(),
+		header: req.Header.Clone(),
+		start:  time.Now(),
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		body := &capture{rc: req.Body}
+		req = req.Clone(req.Context())
+		req.Body = body
+		ex.reqBody = body
+	}
+	t.o.addExchange(ex)
+
+	resp, err := t.base.RoundTrip(req)
+	dur := time.Now().Sub(ex.start)
+	status := "error"
+	ex.mu.Lock()
+	ex.dur = dur
+	if err != nil {
+		ex.err = err
+	} else {
+		status = strconv.Itoa(resp.StatusCode)
+		ex.status = resp.Status
+		ex.respHeader = resp.Header.Clone()
+		body := &capture{rc: resp.Body}
+		resp.Body = body
+		ex.respBody = body
+	}
+	ex.mu.Unlock()
+
+	t.o.Observe("http.request", dur,
+		stats.T("method", req.Method),
+		stats.T("host", req.URL.Host),
+		stats.T("status", status),
+	)
+	return resp, err
+}
+
+// exchange is a request made through O.HTTPClient and its response.
+type exchange struct {
+	method, url string
+	header      http.Header
+	reqBody     *capture
+	start       time.Time
+
+	mu         sync.Mutex
+	dur        time.Duration
+	err        error
+	status     string
+	respHeader http.Header
+	respBody   *capture
+}
+
+func (o *O) addExchange(ex *exchange) {
+	o.mu.Lock()
+	o.exchanges = append(o.exchanges, ex)
+	o.mu.Unlock()
+}
+
+// dumpHTTP logs the exchanges made through O.HTTPClient.
+func (o *O) dumpHTTP() {
+	o.mu.Lock()
+	exchanges := append([]*exchange(nil), o.exchanges...)
+	o.mu.Unlock()
+	for _, ex := range exchanges {
+		var b strings.Builder
+		fmt.Fprintf(&b, "=== HTTP %s %s\n", ex.method, ex.url)
+		writeHeader(&b, "> ", ex.header)
+		if ex.reqBody != nil {
+			b.WriteString(ex.reqBody.String())
+		}
+		ex.mu.Lock()
+		switch {
+		case ex.err != nil:
+			fmt.Fprintf(&b, "< error: %v (%s)\n", ex.err, ex.dur)
+		case ex.status != "":
+			fmt.Fprintf(&b, "< %s (%s)\n", ex.status, ex.dur)
+			writeHeader(&b, "< ", ex.respHeader)
+			b.WriteString(ex.respBody.String())
+		default:
+			b.WriteString("< no response yet\n")
+		}
+		ex.mu.Unlock()
+		o.Log(strings.TrimSuffix(b.String(), "\n"))
+	}
+}
+
+func writeHeader(b *strings.Builder, prefix string, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := strings.Join(h[k], ", ")
+		if redactedHeaders[k] {
+			v = "[redacted]"
+		}
+		fmt.Fprintf(b, "%s%s: %s\n", prefix, k, v)
+	}
+}
+
+// capture wraps a body, keeping the first httpBodyLimit bytes read from it.
+type capture struct {
+	rc io.ReadCloser
+
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (c *capture) Read(p []byte) (int, error) {
+	n, err := c.rc.Read(p)
+	c.mu.Lock()
+	keep := n
+	if room := httpBodyLimit - c.buf.Len(); keep > room {
+		keep = room
+		c.truncated = true
+	}
+	c.buf.Write(p[:keep])
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *capture) Close() error {
+	return c.rc.Close()
+}
+
+// String returns the captured body as a block of text, or "" if nothing was
+// read.
+func (c *capture) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.buf.Len() == 0 {
+		return ""
+	}
+	s := c.buf.String()
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	if c.truncated {
+		s += fmt.Sprintf("... (truncated to %d bytes)\n", httpBodyLimit)
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/stats"
+	"github.com/segmentio/stats/statstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Traceparent") == "" {
+			t.Error("missing traceparent")
+		}
+		w.Header().Set("Set-Cookie", "session=secret")
+		if r.URL.Path == "/big" {
+			w.Write([]byte(strings.Repeat("x", 2*httpBodyLimit)))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusTeapot)
+		w.Write(append([]byte("echo "), body...))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		fail   bool
+		dumped bool
+	}{
+		{"pass", false, false},
+		{"fail", true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := &statstest.Handler{}
+			s := New(WithStats(stats.NewEngine("", h)), WithOutput(ioutil.Discard))
+			run := startCase(t, s, TestCase{
+				Name:   "smoke",
+				Period: time.Hour,
+				// Run on the watchdog goroutine, so it mustn't call
+				// t.FailNow through require
+				Func: func(ctx context.Context, o *O) {
+					c := o.HTTPClient()
+					req, _ := http.NewRequest("POST", srv.URL+"/echo", strings.NewReader("hello"))
+					req.Header.Set("Authorization", "Bearer secret")
+					resp, err := c.Do(req)
+					if !assert.NoError(t, err) {
+						return
+					}
+					b, _ := ioutil.ReadAll(resp.Body)
+					resp.Body.Close()
+					assert.Equal(t, "echo hello", string(b))
+
+					resp, err = c.Get(srv.URL + "/big")
+					if !assert.NoError(t, err) {
+						return
+					}
+					ioutil.ReadAll(resp.Body)
+					resp.Body.Close()
+
+					_, err = c.Get("http://127.0.0.1:1/refused")
+					assert.Error(t, err)
+					if test.fail {
+						o.Error("failed")
+					}
+				},
+			})
+			defer s.Close()
+			last := run()
+
+			var statuses []string
+			for _, m := range measures(h, "http.request") {
+				assert.Equal(t, "smoke", tagValue(m.Tags, "case"))
+				statuses = append(statuses, tagValue(m.Tags, "status"))
+			}
+			assert.Equal(t, []string{"418", "200", "error"}, statuses)
+
+			assert.Equal(t, test.dumped, strings.Contains(last.Output, "=== HTTP POST "+srv.URL+"/echo"))
+			if !test.dumped {
+				return
+			}
+			assert.Contains(t, last.Output, "> Authorization: [redacted]")
+			assert.Contains(t, last.Output, "hello\n")
+			assert.Contains(t, last.Output, "< 418 I'm a teapot")
+			assert.Contains(t, last.Output, "echo hello")
+			assert.Contains(t, last.Output, "< Set-Cookie: [redacted]")
+			assert.NotContains(t, last.Output, "secret")
+			assert.Contains(t, last.Output, "... (truncated to 4096 bytes)")
+			assert.NotContains(t, last.Output, strings.Repeat("x", httpBodyLimit+1))
+			assert.Contains(t, last.Output, "=== HTTP GET http://127.0.0.1:1/refused")
+			assert.Contains(t, last.Output, "< error: ")
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package orbital
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/stats"
+)
+
+// LeakMode selects what happens when a run leaks goroutines.
+type LeakMode int
+
+const (
+	// LeakOff disables leak detection.
+	LeakOff LeakMode = iota
+	// LeakWarn logs leaked goroutines without failing the run.
+	LeakWarn
+	// LeakFail fails the run when it leaks goroutines.
+	LeakFail
+)
+
+// runLabel is the pprof label identifying the run a goroutine belongs to.
+// Goroutines inherit labels from the goroutine which starts them, so every
+// goroutine started by a TestFunc carries its run ID.
+const runLabel = "orbital_run"
+
+// DefaultLeakIgnores are stack fragments of goroutines which outlive a run
+// without being leaks, such as pooled HTTP connections.
+var DefaultLeakIgnores = []string{
+	"net/http.(*persistConn).readLoop",
+	"net/http.(*persistConn).writeLoop",
+	"net/http.(*http2ClientConn).readLoop",
+}
+
+// WithLeakDetection makes the Service look for goroutines started by a run
+// which are still running once it returns.  Goroutines whose stack contains
+// any of ignore, or of DefaultLeakIgnores, are not counted.  The number of
+// leaked goroutines is set on the "goroutines.leaked" gauge after every run.
+func WithLeakDetection(mode LeakMode, ignore ...string) func(*Service) {
+	return func(svc *Service) {
+		svc.leakMode = mode
+		svc.leakIgnore = append(append([]string(nil), DefaultLeakIgnores...), ignore...)
+	}
+}
+
+// leakSettle is how long goroutines started by a run are given to exit after
+// it returns before they count as leaked.
+const leakSettle = 200 * time.Millisecond
+
+// labeled runs fn with the pprof label of the run if leak detection is on.
+func (s *Service) labeled(ctx context.Context, o *O, fn func(context.Context)) {
+	if s.leakMode == LeakOff {
+		fn(ctx)
+		return
+	}
+	pprof.Do(ctx, pprof.Labels(runLabel, o.id), fn)
+}
+
+// checkLeaks reports the goroutines leaked by the run of o.
+func (s *Service) checkLeaks(o *O, tc TestCase) {
+	if s.leakMode == LeakOff {
+		return
+	}
+	// Goroutines exit in real time whatever the clock of the Service, and
+	// nothing would advance a FakeClock while the run waits for them
+	var leaked []leak
+	deadline := time.Now().Add(leakSettle)
+	for {
+		leaked = runGoroutines(o.id, s.leakIgnore)
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			break
+		}
+		t := time.NewTimer(10 * time.Millisecond)
+		<-t.C
+	}
+	n := 0
+	for _, l := range leaked {
+		n += l.count
+	}
+
+	tags := append([]stats.Tag{
+		stats.T("case", tc.Name),
+	}, tc.Tags...)
+	s.stats.Set("goroutines.leaked", n, tags...)
+	o.mu.Lock()
+	o.leaked = n
+	o.mu.Unlock()
+	if n == 0 {
+		return
+	}
+	if s.leakMode == LeakFail {
+		o.Errorf("%d goroutines leaked", n)
+	} else {
+		o.Logf("warning: %d goroutines leaked", n)
+	}
+	for _, l := range leaked {
+		o.Log(l.stack)
+	}
+}
+
+// leak is a group of goroutines with the same stack.
+type leak struct {
+	count int
+	stack string
+}
+
+// runGoroutines returns the live goroutines labeled with the run ID,
+// skipping those matching ignore.
+func runGoroutines(id string, ignore []string) []leak {
+	var buf bytes.Buffer
+	pprof.Lookup("goroutine").WriteTo(&buf, 1)
+	profile := buf.String()
+	// Skip the "goroutine profile: total N" header
+	if i := strings.IndexByte(profile, '\n'); i >= 0 {
+		profile = profile[i+1:]
+	}
+	label := `"` + runLabel + `":"` + id + `"`
+
+	var out []leak
+	// Goroutines with identical stacks and labels are grouped into blocks
+	// separated by blank lines, each starting with "<count> @ <pcs>"
+	for _, block := range strings.Split(profile, "\n\n") {
+		if !strings.Contains(block, label) || matchesAny(block, ignore) {
+			continue
+		}
+		var n int
+		if i := strings.IndexByte(block, ' '); i > 0 {
+			n, _ = strconv.Atoi(block[:i])
+		}
+		if n > 0 {
+			out = append(out, leak{count: n, stack: block})
+		}
+	}
+	return out
+}
+
+func matchesAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
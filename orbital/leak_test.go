@@ -0,0 +1,71 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/segmentio/stats"
+	"github.com/segmentio/stats/statstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func leakyBackground(stop chan struct{}) {
+	<-stop
+}
+
+func TestLeakDetection(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	leaky := func(ctx context.Context, o *O) {
+		go leakyBackground(stop)
+		go leakyBackground(stop)
+	}
+	tidy := func(ctx context.Context, o *O) {
+		done := make(chan struct{})
+		go func() { close(done) }()
+		<-done
+	}
+
+	tests := []struct {
+		scenario string
+		mode     LeakMode
+		ignore   []string
+		fn       TestFunc
+		leaked   int
+		failed   bool
+	}{
+		{scenario: "fail", mode: LeakFail, fn: leaky, leaked: 2, failed: true},
+		{scenario: "warn", mode: LeakWarn, fn: leaky, leaked: 2},
+		{scenario: "ignored", mode: LeakFail, ignore: []string{"orbital.leakyBackground"}, fn: leaky},
+		{scenario: "tidy", mode: LeakFail, fn: tidy},
+		{scenario: "off", mode: LeakOff, fn: leaky},
+	}
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			h := &statstest.Handler{}
+			s := New(
+				WithStats(stats.NewEngine("", h)),
+				WithLeakDetection(test.mode, test.ignore...),
+				WithOutput(ioutil.Discard),
+			)
+			run := startCase(t, s, TestCase{Name: "leak_test", Period: time.Hour, Func: test.fn})
+			defer s.Close()
+
+			last := run()
+			assert.Equal(t, test.leaked, last.LeakedGoroutines)
+			assert.Equal(t, test.failed, last.Failed)
+			assert.True(t, last.Duration < leakSettle, "the leak check should not count in the duration, got %s", last.Duration)
+			if test.leaked > 0 {
+				assert.Contains(t, last.Output, "orbital.leakyBackground")
+			}
+			if test.mode != LeakOff {
+				gauges := measures(h, "goroutines.leaked")
+				require.Len(t, gauges, 1)
+				assert.Equal(t, int64(test.leaked), gauges[0].Fields[0].Value.Int())
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/ksuid"
+	"github.com/segmentio/stats"
+)
+
+// LoadCase runs a TestFunc continuously to generate load, rather than on a
+// schedule.  Exactly one of Rate, a fixed number of runs started per second,
+// or Concurrency, a fixed number of runs in flight, must be set.  If Duration
+// is zero the load runs until the Service is closed.
+//
+// Every run is observed in the "load" histogram, tagged with the case name,
+// result and Tags.  Runs of a LoadCase don't retry, alert or log; their
+// output is discarded.  Like a TestCase, a run still going hangGrace after
+// its Timeout is abandoned and counted as an error.
+type LoadCase struct {
+	Name        string
+	Func        TestFunc
+	Rate        float64
+	Concurrency int
+	Duration    time.Duration
+	// Timeout bounds each run.  If zero, the Service default is used.
+	Timeout time.Duration
+	// MaxInFlight caps the runs in flight at a fixed Rate.  Runs which would
+	// exceed it are dropped and counted in "load.dropped".  Defaults to
+	// 1000.
+	MaxInFlight int
+	Tags        []stats.Tag
+}
+
+// LoadStatus is a snapshot of a LoadCase.
+type LoadStatus struct {
+	Name     string      `json:"name"`
+	Tags     []stats.Tag `json:"tags,omitempty"`
+	Running  bool        `json:"running"`
+	Runs     int64       `json:"runs"`
+	Errors   int64       `json:"errors"`
+	Dropped  int64       `json:"dropped,omitempty"`
+	InFlight int64       `json:"in_flight"`
+	// Throughput is the number of runs completed per second over the last
+	// loadWindow seconds.
+	Throughput float64 `json:"throughput"`
+}
+
+// AddLoad registers lc to be run alongside the scheduled TestCases once the
+// Service is started, or starts it if the Service is already running.  An
+// error is returned if lc is invalid or its name is already taken.
+func (s *Service) AddLoad(lc LoadCase) error {
+	if !validName.MatchString(lc.Name) {
+		return errors.Errorf("load case name %q must match %s", lc.Name, validName)
+	}
+	if lc.Func == nil {
+		return errors.Errorf("%s: Func is nil", lc.Name)
+	}
+	if (lc.Rate > 0) == (lc.Concurrency > 0) {
+		return errors.Errorf("%s: exactly one of Rate and Concurrency must be positive", lc.Name)
+	}
+	if lc.Rate < 0 || lc.Concurrency < 0 || lc.Duration < 0 || lc.Timeout < 0 || lc.MaxInFlight < 0 {
+		return errors.Errorf("%s: negative values are not allowed", lc.Name)
+	}
+	if lc.Rate > 0 && time.Duration(float64(time.Second)/lc.Rate) <= 0 {
+		return errors.Errorf("%s: Rate must be at most 1e9, got %g", lc.Name, lc.Rate)
+	}
+	if lc.MaxInFlight == 0 {
+		lc.MaxInFlight = 1000
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tests {
+		if t.Name == lc.Name {
+			return errors.Errorf("%s already registered as a test case", lc.Name)
+		}
+	}
+	for _, l := range s.loads {
+		if l.lc.Name == lc.Name {
+			return errors.Errorf("load case %s already registered", lc.Name)
+		}
+	}
+	l := &loadState{lc: lc}
+	s.loads = append(s.loads, l)
+	if s.started {
+		s.wg.Add(1)
+		go s.runLoad(l)
+	}
+	return nil
+}
+
+// loadWindow is the number of seconds over which throughput is measured.
+const loadWindow = 10
+
+// loadState tracks a running LoadCase.
+type loadState struct {
+	lc LoadCase
+
+	running  int32
+	runs     int64
+	errors   int64
+	dropped  int64
+	inFlight int64
+
+	mu sync.Mutex
+	// completed runs per second, indexed by unix second modulo loadWindow
+	buckets [loadWindow]struct {
+		sec int64
+		n   int64
+	}
+}
+
+func (l *loadState) complete(now time.Time, failed bool) {
+	atomic.AddInt64(&l.runs, 1)
+	if failed {
+		atomic.AddInt64(&l.errors, 1)
+	}
+	sec := now.Unix()
+	l.mu.Lock()
+	b := &l.buckets[sec%loadWindow]
+	if b.sec != sec {
+		b.sec, b.n = sec, 0
+	}
+	b.n++
+	l.mu.Unlock()
+}
+
+func (l *loadState) throughput(now time.Time) float64 {
+	sec := now.Unix()
+	var n int64
+	l.mu.Lock()
+	for _, b := range l.buckets {
+		if sec-b.sec < loadWindow {
+			n += b.n
+		}
+	}
+	l.mu.Unlock()
+	return float64(n) / loadWindow
+}
+
+func (l *loadState) status(now time.Time) LoadStatus {
+	return LoadStatus{
+		Name:       l.lc.Name,
+		Tags:       l.lc.Tags,
+		Running:    atomic.LoadInt32(&l.running) != 0,
+		Runs:       atomic.LoadInt64(&l.runs),
+		Errors:     atomic.LoadInt64(&l.errors),
+		Dropped:    atomic.LoadInt64(&l.dropped),
+		InFlight:   atomic.LoadInt64(&l.inFlight),
+		Throughput: l.throughput(now),
+	}
+}
+
+// LoadStatus returns a snapshot of every LoadCase, sorted by name.
+func (s *Service) LoadStatus() []LoadStatus {
+	s.mu.Lock()
+	loads := append([]*loadState(nil), s.loads...)
+	s.mu.Unlock()
+	now := s.clock.Now()
+	out := make([]LoadStatus, len(loads))
+	for i, l := range loads {
+		out[i] = l.status(now)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// runLoad generates load for l until its Duration elapses or the Service is
+// closed.
+func (s *Service) runLoad(l *loadState) {
+	defer s.wg.Done()
+	atomic.StoreInt32(&l.running, 1)
+	defer atomic.StoreInt32(&l.running, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if l.lc.Duration > 0 {
+		ctx, cancel = withTimeout(ctx, s.clock, l.lc.Duration)
+		defer cancel()
+	}
+	go func() {
+		select {
+		case <-s.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	if l.lc.Concurrency > 0 {
+		for i := 0; i < l.lc.Concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for ctx.Err() == nil {
+					s.loadOnce(ctx, l)
+				}
+			}()
+		}
+		return
+	}
+
+	tick := s.clock.NewTicker(time.Duration(float64(time.Second) / l.lc.Rate))
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C():
+		case <-ctx.Done():
+			return
+		}
+		if atomic.LoadInt64(&l.inFlight) >= int64(l.lc.MaxInFlight) {
+			atomic.AddInt64(&l.dropped, 1)
+			s.stats.Incr("load.dropped", append([]stats.Tag{stats.T("case", l.lc.Name)}, l.lc.Tags...)...)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loadOnce(ctx, l)
+		}()
+	}
+}
+
+// loadOnce makes a single run of a LoadCase.
+func (s *Service) loadOnce(ctx context.Context, l *loadState) {
+	atomic.AddInt64(&l.inFlight, 1)
+	defer atomic.AddInt64(&l.inFlight, -1)
+
+	to := s.defaultTimeout
+	if l.lc.Timeout > 0 {
+		to = l.lc.Timeout
+	}
+	c, cancel := withTimeout(ctx, s.clock, to)
+	defer cancel()
+	o := &O{
+		w:     ioutil.Discard,
+		id:    ksuid.New().String(),
+		ctx:   c,
+		stats: s.stats,
+		clock: s.clock,
+		tags:  append([]stats.Tag{stats.T("case", l.lc.Name)}, l.lc.Tags...),
+	}
+	start := s.clock.Now()
+	// Under the watchdog, so that a Func which ignores ctx can't hold up the
+	// worker, or Close, for good
+	hung := s.call(c, o, TestCase{Name: l.lc.Name, Func: l.lc.Func, Tags: l.lc.Tags}, to)
+	end := s.clock.Now()
+	// Runs cut short by the end of the load are not counted
+	if ctx.Err() != nil && !hung {
+		return
+	}
+	failed := hung || o.Failed() || c.Err() != nil
+
+	result := "pass"
+	if failed {
+		result = "fail"
+	}
+	tags := append([]stats.Tag{
+		stats.T("case", l.lc.Name),
+		stats.T("result", result),
+	}, l.lc.Tags...)
+	s.stats.Observe("load", end.Sub(start), tags...)
+	l.complete(end, failed)
+}
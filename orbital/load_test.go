@@ -0,0 +1,148 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/segmentio/stats"
+	"github.com/segmentio/stats/statstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddLoad(t *testing.T) {
+	fn := func(ctx context.Context, o *O) {}
+	cases := []struct {
+		name string
+		lc   LoadCase
+	}{
+		{"bad name", LoadCase{Name: "a b", Func: fn, Rate: 1}},
+		{"no func", LoadCase{Name: "load", Rate: 1}},
+		{"neither", LoadCase{Name: "load", Func: fn}},
+		{"both", LoadCase{Name: "load", Func: fn, Rate: 1, Concurrency: 1}},
+		{"negative duration", LoadCase{Name: "load", Func: fn, Rate: 1, Duration: -1}},
+		{"rate too high", LoadCase{Name: "load", Func: fn, Rate: 2e9}},
+		{"clashes with test case", LoadCase{Name: "smoke", Func: fn, Rate: 1}},
+	}
+	s := New()
+	require.NoError(t, s.Add(TestCase{Name: "smoke", Period: time.Hour, Func: fn}))
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Error(t, s.AddLoad(c.lc))
+		})
+	}
+	require.NoError(t, s.AddLoad(LoadCase{Name: "load", Func: fn, Rate: 1}))
+	assert.Error(t, s.AddLoad(LoadCase{Name: "load", Func: fn, Concurrency: 1}))
+	assert.Error(t, s.Add(TestCase{Name: "load", Period: time.Hour, Func: fn}))
+}
+
+func TestLoadConcurrency(t *testing.T) {
+	h := &statstest.Handler{}
+	var inFlight, peak, n int64
+	s := New(WithStats(stats.NewEngine("", h)))
+	s.w = ioutil.Discard
+	require.NoError(t, s.AddLoad(LoadCase{
+		Name:        "load",
+		Concurrency: 3,
+		Duration:    200 * time.Millisecond,
+		Tags:        []stats.Tag{stats.T("team", "core")},
+		Func: func(ctx context.Context, o *O) {
+			cur := atomic.AddInt64(&inFlight, 1)
+			defer atomic.AddInt64(&inFlight, -1)
+			for {
+				p := atomic.LoadInt64(&peak)
+				if cur <= p || atomic.CompareAndSwapInt64(&peak, p, cur) {
+					break
+				}
+			}
+			if atomic.AddInt64(&n, 1)%2 == 0 {
+				o.Error("even")
+			}
+			time.Sleep(5 * time.Millisecond)
+		},
+	}))
+	require.NoError(t, s.Run())
+	defer s.Close()
+
+	time.Sleep(100 * time.Millisecond)
+	st := s.LoadStatus()
+	require.Len(t, st, 1)
+	assert.True(t, st[0].Running)
+	assert.True(t, st[0].Throughput > 0)
+
+	time.Sleep(200 * time.Millisecond)
+	st = s.LoadStatus()
+	assert.False(t, st[0].Running)
+	assert.Equal(t, int64(3), atomic.LoadInt64(&peak))
+	assert.True(t, st[0].Runs > 10)
+	assert.True(t, st[0].Errors > 0 && st[0].Errors < st[0].Runs)
+	assert.Zero(t, st[0].InFlight)
+
+	var pass, fail int
+	for _, m := range measures(h, "load") {
+		assert.Equal(t, "core", tagValue(m.Tags, "team"))
+		switch tagValue(m.Tags, "result") {
+		case "pass":
+			pass++
+		case "fail":
+			fail++
+		}
+	}
+	assert.Equal(t, int(st[0].Runs), pass+fail)
+	assert.True(t, fail > 0)
+}
+
+func TestLoadRate(t *testing.T) {
+	var n int64
+	s := New()
+	s.w = ioutil.Discard
+	require.NoError(t, s.AddLoad(LoadCase{
+		Name: "load",
+		Rate: 100,
+		Func: func(ctx context.Context, o *O) {
+			atomic.AddInt64(&n, 1)
+		},
+	}))
+	require.NoError(t, s.Run())
+	time.Sleep(300 * time.Millisecond)
+	s.Close()
+
+	got := atomic.LoadInt64(&n)
+	assert.True(t, got >= 15 && got <= 35, "got %d runs", got)
+	assert.False(t, s.LoadStatus()[0].Running)
+}
+
+func TestLoadHung(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	s := New(WithHangGrace(10 * time.Millisecond))
+	s.w = ioutil.Discard
+	require.NoError(t, s.Run())
+	// Added after Run, so started straight away
+	require.NoError(t, s.AddLoad(LoadCase{
+		Name:        "load",
+		Concurrency: 1,
+		Timeout:     10 * time.Millisecond,
+		Func: func(ctx context.Context, o *O) {
+			<-block
+		},
+	}))
+	time.Sleep(100 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked on a hung load run")
+	}
+	st := s.LoadStatus()[0]
+	assert.True(t, st.Runs > 0)
+	assert.Equal(t, st.Runs, st.Errors)
+}
@@ -0,0 +1,116 @@
+package orbital
+
+import (
+	"time"
+
+	"github.com/segmentio/stats"
+)
+
+// Metric is a value recorded through O during a run.
+type Metric struct {
+	Name string `json:"name"`
+	// Type is one of counter, gauge or histogram.
+	Type string `json:"type"`
+	// Value is the recorded value.  Durations are in seconds.
+	Value float64     `json:"value"`
+	Tags  []stats.Tag `json:"tags,omitempty"`
+	Time  time.Time   `json:"time"`
+}
+
+// Observe records value in the histogram name, tagged with the case name, the
+// TestCase tags, the current step and tags.
+func (o *O) Observe(name string, value interface{}, tags ...stats.Tag) {
+	tags = o.metricTags(tags)
+	o.stats.Observe(name, value, tags...)
+	o.addMetric(name, "histogram", value, tags)
+}
+
+// Incr increments the counter name, tagged with the case name, the TestCase
+// tags, the current step and tags.
+func (o *O) Incr(name string, tags ...stats.Tag) {
+	tags = o.metricTags(tags)
+	o.stats.Incr(name, tags...)
+	o.addMetric(name, "counter", 1, tags)
+}
+
+// Set sets the gauge name to value, tagged with the case name, the TestCase
+// tags, the current step and tags.
+func (o *O) Set(name string, value interface{}, tags ...stats.Tag) {
+	tags = o.metricTags(tags)
+	o.stats.Set(name, value, tags...)
+	o.addMetric(name, "gauge", value, tags)
+}
+
+// Clock starts a Clock observing durations in the histogram name.
+func (o *O) Clock(name string, tags ...stats.Tag) *Clock {
+	now := o.timeSource().Now()
+	return &Clock{o: o, name: name, tags: tags, first: now, last: now}
+}
+
+// Clock measures sequential durations within a run, like stats.Clock, but
+// records them through O.  It is not safe for concurrent use.
+type Clock struct {
+	o           *O
+	name        string
+	tags        []stats.Tag
+	first, last time.Time
+}
+
+// Stamp observes the time since the last call to Stamp, or since the Clock was
+// created, with the tag stamp=name.
+func (c *Clock) Stamp(name string) {
+	now := c.o.timeSource().Now()
+	c.o.Observe(c.name, now.Sub(c.last), append(c.tags, stats.T("stamp", name))...)
+	c.last = now
+}
+
+// Stop observes the time since the Clock was created with the tag
+// stamp=total.
+func (c *Clock) Stop() {
+	c.o.Observe(c.name, c.o.timeSource().Now().Sub(c.first), append(c.tags, stats.T("stamp", "total"))...)
+}
+
+// metricTags returns the tags every metric recorded through o carries,
+// including the current step if any, followed by extra.
+func (o *O) metricTags(extra []stats.Tag) []stats.Tag {
+	tags := make([]stats.Tag, 0, len(o.tags)+len(extra)+1)
+	tags = append(tags, o.tags...)
+	o.mu.Lock()
+	if o.step != "" {
+		tags = append(tags, stats.T("step", o.step))
+	}
+	o.mu.Unlock()
+	return append(tags, extra...)
+}
+
+func (o *O) addMetric(name, typ string, value interface{}, tags []stats.Tag) {
+	m := Metric{
+		Name:  name,
+		Type:  typ,
+		Value: floatValue(value),
+		Tags:  tags,
+		Time:  o.timeSource().Now(),
+	}
+	o.mu.Lock()
+	o.metrics = append(o.metrics, m)
+	o.mu.Unlock()
+}
+
+func floatValue(v interface{}) float64 {
+	sv := stats.ValueOf(v)
+	switch sv.Type() {
+	case stats.Bool:
+		if sv.Bool() {
+			return 1
+		}
+	case stats.Int:
+		return float64(sv.Int())
+	case stats.Uint:
+		return float64(sv.Uint())
+	case stats.Float:
+		return sv.Float()
+	case stats.Duration:
+		return sv.Duration().Seconds()
+	}
+	return 0
+}
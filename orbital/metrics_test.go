@@ -0,0 +1,83 @@
+package orbital
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/segmentio/stats"
+	"github.com/segmentio/stats/statstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// measures returns the measures named name handled by h.
+func measures(h *statstest.Handler, name string) []stats.Measure {
+	var ms []stats.Measure
+	for _, m := range h.Measures() {
+		if m.Name == name {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+func tagValue(tags []stats.Tag, name string) string {
+	for _, t := range tags {
+		if t.Name == name {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+func TestOMetrics(t *testing.T) {
+	h := &statstest.Handler{}
+	s := New(WithStats(stats.NewEngine("", h)), WithOutput(ioutil.Discard))
+	run := startCase(t, s, TestCase{
+		Name:   "metrics_test",
+		Period: time.Hour,
+		Tags:   []stats.Tag{stats.T("region", "us-west-2")},
+		Func: func(ctx context.Context, o *O) {
+			o.Incr("events", stats.T("kind", "track"))
+			o.Set("queue_depth", 12)
+			o.Observe("lag", 1500*time.Millisecond)
+			c := o.Clock("phase")
+			c.Stamp("send")
+			c.Stop()
+		},
+	})
+	defer s.Close()
+	last := run()
+
+	for _, name := range []string{"events", "queue_depth", "lag", "phase"} {
+		ms := measures(h, name)
+		require.NotEmpty(t, ms, name)
+		for _, m := range ms {
+			assert.Equal(t, "metrics_test", tagValue(m.Tags, "case"), name)
+			assert.Equal(t, "us-west-2", tagValue(m.Tags, "region"), name)
+		}
+	}
+	assert.Equal(t, "track", tagValue(measures(h, "events")[0].Tags, "kind"))
+
+	require.Len(t, last.Metrics, 5)
+	assert.Equal(t, "counter", last.Metrics[0].Type)
+	assert.Equal(t, 12.0, last.Metrics[1].Value)
+	assert.Equal(t, 1.5, last.Metrics[2].Value)
+	assert.Equal(t, "send", tagValue(last.Metrics[3].Tags, "stamp"))
+	assert.Equal(t, "total", tagValue(last.Metrics[4].Tags, "stamp"))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	var status struct {
+		Cases []CaseStatus `json:"cases"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	require.Len(t, status.Cases, 1)
+	assert.Equal(t, "ok", status.Cases[0].Alert)
+	require.NotNil(t, status.Cases[0].Last)
+	assert.Len(t, status.Cases[0].Last.Metrics, 5)
+}
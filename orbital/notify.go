@@ -0,0 +1,135 @@
+package orbital
+
+import (
+	"context"
+	"sync"
+)
+
+// AlertStatus is the alerting state of a TestCase.
+type AlertStatus int
+
+const (
+	// StatusOK means the TestCase is passing, or has not failed often enough
+	// to alert.
+	StatusOK AlertStatus = iota
+	// StatusFailing means the TestCase has failed FailAfter times in a row.
+	StatusFailing
+	// StatusRecovered means a failing TestCase has passed RecoverAfter times
+	// in a row.  It is only ever seen on an Alert; the TestCase itself goes
+	// straight back to StatusOK.
+	StatusRecovered
+	// StatusBudgetBurn means the error budget of the SLO of a TestCase has
+	// started burning fast.  Like StatusRecovered, it is only ever seen on an
+	// Alert.
+	StatusBudgetBurn
+)
+
+func (s AlertStatus) String() string {
+	switch s {
+	case StatusOK:
+		return "ok"
+	case StatusFailing:
+		return "failing"
+	case StatusRecovered:
+		return "recovered"
+	case StatusBudgetBurn:
+		return "budget_burn"
+	}
+	return "unknown"
+}
+
+// Alert describes a transition in the alerting state of a TestCase.
+type Alert struct {
+	Status AlertStatus
+	Case   TestCase
+	// Result is the run which caused the transition.
+	Result Result
+	// LastFailure is the most recent failed run.  On StatusFailing it is the
+	// same as Result.
+	LastFailure Result
+	// Failures is the number of consecutive failures seen before the
+	// transition.
+	Failures int
+	// SLO is the state of the SLO of the TestCase on StatusBudgetBurn.
+	SLO *SLOStatus
+}
+
+// Notifier is told about alert transitions.  Notify is only called when the
+// state changes, so repeated failures of an already failing TestCase do not
+// produce repeated notifications.
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface.
+type NotifierFunc func(ctx context.Context, a Alert) error
+
+// Notify calls f(ctx, a).
+func (f NotifierFunc) Notify(ctx context.Context, a Alert) error {
+	return f(ctx, a)
+}
+
+// alertState is the per TestCase alert state machine.
+type alertState struct {
+	mu          sync.Mutex
+	status      AlertStatus
+	failures    int
+	passes      int
+	lastFailure Result
+	// length of the last run of failures, reported on recovery
+	streak int
+}
+
+// record feeds r into the state machine and returns the resulting Alert if
+// the state changed.
+func (a *alertState) record(tc TestCase, r Result, failAfter, recoverAfter int) (Alert, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if r.Failed {
+		a.failures++
+		a.passes = 0
+		a.lastFailure = r
+		if a.status == StatusOK && a.failures >= failAfter {
+			a.status = StatusFailing
+			return Alert{
+				Status:      StatusFailing,
+				Case:        tc,
+				Result:      r,
+				LastFailure: r,
+				Failures:    a.failures,
+			}, true
+		}
+		return Alert{}, false
+	}
+
+	if a.failures > 0 {
+		a.streak = a.failures
+	}
+	a.failures = 0
+	a.passes++
+	if a.status == StatusFailing && a.passes >= recoverAfter {
+		a.status = StatusOK
+		return Alert{
+			Status:      StatusRecovered,
+			Case:        tc,
+			Result:      r,
+			LastFailure: a.lastFailure,
+			Failures:    a.streak,
+		}, true
+	}
+	return Alert{}, false
+}
+
+// consecutive returns the number of failures since the last pass.
+func (a *alertState) consecutive() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.failures
+}
+
+// current returns the alert status without changing it.
+func (a *alertState) current() AlertStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.status
+}
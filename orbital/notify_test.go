@@ -0,0 +1,63 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertState(t *testing.T) {
+	tc := TestCase{Name: "alert_test"}
+	pass := Result{Name: tc.Name}
+	fail := Result{Name: tc.Name, Failed: true}
+
+	var a alertState
+	var seen []AlertStatus
+	for _, r := range []Result{fail, pass, fail, fail, fail, fail, pass, fail, pass, pass, pass} {
+		if alert, ok := a.record(tc, r, 2, 2); ok {
+			seen = append(seen, alert.Status)
+		}
+	}
+	assert.Equal(t, []AlertStatus{StatusFailing, StatusRecovered}, seen)
+}
+
+func TestServiceNotify(t *testing.T) {
+	var alerts []Alert
+	s := New(WithNotifier(NotifierFunc(func(ctx context.Context, a Alert) error {
+		alerts = append(alerts, a)
+		return nil
+	})))
+	s.w = ioutil.Discard
+
+	failing := true
+	tc := TestCase{
+		Name:     "notify_test",
+		Period:   time.Hour,
+		Metadata: map[string]string{"team": "orbital"},
+		Func: func(ctx context.Context, o *O) {
+			if failing {
+				o.Error("boom")
+			}
+		},
+	}
+	require.NoError(t, s.Add(tc))
+	require.NoError(t, s.Run())
+	defer s.Close()
+
+	s.handle(context.Background(), tc)
+	s.handle(context.Background(), tc)
+	failing = false
+	s.handle(context.Background(), tc)
+
+	require.Len(t, alerts, 2)
+	assert.Equal(t, StatusFailing, alerts[0].Status)
+	assert.Equal(t, "boom\n", alerts[0].LastFailure.Output)
+	assert.Equal(t, "orbital", alerts[0].Case.Metadata["team"])
+	assert.Equal(t, StatusRecovered, alerts[1].Status)
+	assert.Equal(t, 2, alerts[1].Failures)
+	assert.False(t, alerts[1].Result.Failed)
+}
@@ -1,9 +1,12 @@
 package orbital
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,13 +15,58 @@ import (
 
 // TestCase represents an individual test to be run on a schedule given by
 // Period.  If Timeout is not specified, Service will provide a default timeout.
-// Name should be metrics-compatible, for now.
+// Name must be metrics-compatible: letters, digits and _ . : / - only.  See
+// Service.Validate.
+//
+// FailAfter and RecoverAfter control when Notifiers are told about the test:
+// an alert fires after FailAfter consecutive failures and resolves after
+// RecoverAfter consecutive passes.  If either is zero, the Service default is
+// used.  Metadata is passed through to Notifiers untouched.
+//
+// Params registers a matrix of TestCases in one go: the TestCase is run once
+// for every combination of parameter values, each under its own name and
+// tagged with its parameters.  The TestFunc reads them with O.Param.  A
+// Service with Environments (see WithEnvironments) likewise runs every
+// TestCase once per Environment.
+//
+// Limits maps a unit given to O.ReportMetric to the highest acceptable value;
+// a run reporting more fails.
+//
+// SLO, if set, tracks the compliance of the TestCase with an objective and
+// notifies when its error budget burns fast.  See SLOStatus.
+//
+// DependsOn lists the names of TestCases this one relies on; the name of a
+// TestCase with Params stands for all of its expansions.  While any of
+// them is failing, runs of this TestCase are skipped rather than failed.
+//
+// Schedule is an alternative to Period: a cron expression as accepted by
+// ParseSchedule.  A failed run is retried up to Retries times before it is
+// reported as a failure.  Disabled TestCases are scheduled but never run,
+// which allows them to be switched back on by a config reload.
 type TestCase struct {
-	Period  time.Duration
-	Name    string
-	Func    TestFunc
-	Timeout time.Duration
-	Tags    []stats.Tag
+	Period    time.Duration
+	Schedule  string
+	Name      string
+	Func      TestFunc
+	Timeout   time.Duration
+	Tags      []stats.Tag
+	Retries   int
+	Disabled  bool
+	Limits    map[string]float64
+	Params    map[string][]string
+	DependsOn []string
+
+	FailAfter    int
+	RecoverAfter int
+	Metadata     map[string]string
+	SLO          *SLO
+
+	// parameter values of a TestCase expanded from Params, and the name it
+	// was registered under
+	params map[string]string
+	group  string
+	// target of a TestCase expanded from the Service Environments
+	env Environment
 }
 
 // TestFunc represents a function to be run under test
@@ -33,36 +81,86 @@ type TestFunc func(ctx context.Context, o *O)
 type O struct {
 	// output writer
 	w io.Writer
+	// run ID
+	id string
+
+	stats *stats.Engine
+	clock TimeSource
+	// run context, or that of the step in progress, carrying its span
+	ctx      context.Context
+	exporter SpanExporter
+	// tags applied to metrics recorded through O
+	tags    []stats.Tag
+	metrics []Metric
+	// name of the step in progress, and results of those completed
+	step       string
+	steps      []StepResult
+	failedStep string
+	// values passed to ReportMetric, by unit
+	reported map[string]float64
+	// goroutines leaked by the run
+	leaked int
+	// parameter values, see TestCase.Params
+	params map[string]string
+	// see O.Env
+	env Environment
+	// requests made through HTTPClient
+	exchanges []*exchange
+	// structured failures, see AddFailure
+	failures []Failure
 
-	stats  *stats.Engine
 	failed bool
-	mu     sync.Mutex
+	// number of calls which failed the test, so that a step can tell
+	// whether it failed after an earlier one did
+	fails int
+	// messages passed to Error and Errorf
+	errors []string
+	// copy of everything written to w, kept for the Result
+	out bytes.Buffer
+	mu  sync.Mutex
 }
 
 // Error is equivalent to Log followed by Fail
 func (o *O) Error(args ...interface{}) {
-	o.log(fmt.Sprintln(args...))
-	o.Fail()
+	s := fmt.Sprintln(args...)
+	o.log(s)
+	o.error(s)
 }
 
 // Errorf is equivalent to Logf followed by Fail
 func (o *O) Errorf(fstr string, args ...interface{}) {
-	o.log(fmt.Sprintf(fstr, args...))
-	o.Fail()
+	s := fmt.Sprintf(fstr, args...)
+	o.log(s)
+	o.error(s)
 }
 
-// Fatal functions exist in testing.T because they call
-// runtime.Goexit to prevent having to deal with context
-// and because it's convenient.
-// Not sure they're worth having in an always running
-// daemon
-// func (o *O) Fatal(args ...interface{}) {
-// 	o.log(fmt.Sprintln(args...))
-// }
-//
-// func (o *O) Fatalf(fstr string, args ...interface{}) {
-// 	o.log(fmt.Sprintf(fstr, args...))
-// }
+func (o *O) error(s string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.errors = append(o.errors, strings.TrimSuffix(s, "\n"))
+	o.failed = true
+	o.fails++
+}
+
+// Fatal is equivalent to Error followed by FailNow
+func (o *O) Fatal(args ...interface{}) {
+	o.Error(args...)
+	o.FailNow()
+}
+
+// Fatalf is equivalent to Errorf followed by FailNow
+func (o *O) Fatalf(fstr string, args ...interface{}) {
+	o.Errorf(fstr, args...)
+	o.FailNow()
+}
+
+// FailNow marks the test as failed and stops it by calling runtime.Goexit.
+// Like testing.T.FailNow, it must be called from the goroutine running the
+// TestFunc, not from goroutines it starts.  Deferred calls still run.
+func (o *O) FailNow() {
+	o.Fail()
+	runtime.Goexit()
+}
 
 func (o *O) Log(args ...interface{}) {
 	o.log(fmt.Sprintln(args...))
@@ -78,14 +176,32 @@ func (o *O) log(s string) {
 	}
 
 	fmt.Fprint(o.w, s)
+	o.mu.Lock()
+	o.out.WriteString(s)
+	o.mu.Unlock()
 }
 
 func (o *O) Fail() {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 	o.failed = true
+	o.fails++
 }
 
+// Stats returns the Service stats engine.  Metrics recorded on it directly
+// are not tagged with the TestCase; prefer Observe, Incr, Set and Clock.
 func (o *O) Stats() *stats.Engine {
 	return o.stats
 }
+
+// Failed reports whether the test has failed.
+func (o *O) Failed() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.failed
+}
+
+// RunID returns the unique ID of the current run.
+func (o *O) RunID() string {
+	return o.id
+}
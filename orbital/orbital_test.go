@@ -2,33 +2,141 @@ package orbital
 
 import (
 	"context"
+	"io/ioutil"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func init() {
+// startCase adds tc to s and runs s, returning a func which runs tc once as
+// a scheduled run would and returns its Result.  The caller closes s.
+func startCase(t *testing.T, s *Service, tc TestCase) func() Result {
+	t.Helper()
+	require.NoError(t, s.Add(tc))
+	require.NoError(t, s.Run())
+	return func() Result {
+		s.handle(context.Background(), tc)
+		last, _ := s.cases[tc.Name].lastResult()
+		return last
+	}
+}
+
+func TestOrbital(t *testing.T) {
+	fc := NewFakeClock(epoch)
+	defer func(s *Service) { DefaultService = s }(DefaultService)
+	DefaultService = New(WithClock(fc), WithOutput(ioutil.Discard))
+
 	Register(TestCase{
-		Period: 400 * time.Microsecond,
+		Period: 3 * time.Minute,
 		Func: func(ctx context.Context, o *O) {
-			time.Sleep(550 * time.Microsecond)
 			o.Log("in test case")
 		},
-		Name: "smoke test",
+		Name: "smoke_test",
 	})
-
 	Register(TestCase{
-		Period: 300 * time.Microsecond,
+		Period: 2 * time.Minute,
 		Func: func(ctx context.Context, o *O) {
-			time.Sleep(500 * time.Microsecond)
 			o.Log("in test case")
 		},
-		Name: "secondary test",
+		Name: "secondary_test",
 	})
+	assert.NoError(t, DefaultService.Validate())
+	require.NoError(t, DefaultService.Run())
+	defer DefaultService.Close()
 
+	// Wait for both tickers before moving the clock
+	fc.BlockUntil(2)
+	want := map[string][]time.Duration{
+		"smoke_test":     {3 * time.Minute, 6 * time.Minute},
+		"secondary_test": {2 * time.Minute, 4 * time.Minute, 6 * time.Minute},
+	}
+	for m := 1; m <= 6; m++ {
+		fc.Advance(time.Minute)
+		for name, at := range want {
+			n := 0
+			for _, d := range at {
+				if d <= time.Duration(m)*time.Minute {
+					n++
+				}
+			}
+			waitHistory(t, DefaultService, name, n)
+		}
+	}
+	for name, at := range want {
+		rs, err := DefaultService.History(name, 0)
+		require.NoError(t, err)
+		require.Len(t, rs, len(at), name)
+		for i, d := range at {
+			assert.Equal(t, epoch.Add(d), rs[i].Start, name)
+			assert.False(t, rs[i].Failed, name)
+			assert.Equal(t, "in test case\n", rs[i].Output, name)
+		}
+	}
 }
 
-func TestOrbital(t *testing.T) {
-	DefaultService.Run()
-	time.Sleep(2 * time.Millisecond)
-	DefaultService.Close()
+func TestHandleRetries(t *testing.T) {
+	s := New()
+	s.w = ioutil.Discard
+
+	attempts := 0
+	tc := TestCase{
+		Name:    "retry_test",
+		Period:  time.Hour,
+		Retries: 2,
+		Func: func(ctx context.Context, o *O) {
+			attempts++
+			if attempts < 3 {
+				o.Error("not yet")
+			}
+		},
+	}
+	require.NoError(t, s.Add(tc))
+	require.NoError(t, s.Run())
+	defer s.Close()
+	s.handle(context.Background(), tc)
+
+	last, _ := s.cases[tc.Name].lastResult()
+	assert.False(t, last.Failed)
+	assert.Equal(t, 3, last.Attempts)
+}
+
+func TestRunOnceRunning(t *testing.T) {
+	s := New(WithOutput(ioutil.Discard))
+	started, release := make(chan struct{}), make(chan struct{})
+	tc := TestCase{
+		Name:   "slow",
+		Period: time.Hour,
+		Func: func(ctx context.Context, o *O) {
+			started <- struct{}{}
+			<-release
+		},
+	}
+	require.NoError(t, s.Add(tc))
+	require.NoError(t, s.Prepare())
+	defer s.Close()
+
+	done := make(chan Result)
+	go func() {
+		r, err := s.RunOnce(context.Background(), "slow", nil)
+		assert.NoError(t, err)
+		done <- r
+	}()
+	<-started
+	_, err := s.RunOnce(context.Background(), "slow", nil)
+	assert.EqualError(t, err, "test case slow is already running")
+	// A scheduled run is dropped rather than overlapping
+	s.handle(context.Background(), tc)
+	s.skipScheduled(tc, "dependency failing")
+	close(release)
+	r := <-done
+	assert.False(t, r.Failed)
+	rs, _ := s.History("slow", 0)
+	assert.Len(t, rs, 1)
+
+	// Once it is done, it can run again
+	go func() { <-started }()
+	_, err = s.RunOnce(context.Background(), "slow", nil)
+	assert.NoError(t, err)
 }
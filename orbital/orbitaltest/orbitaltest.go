@@ -0,0 +1,119 @@
+// Package orbitaltest runs orbital TestCases as Go tests, so that the same
+// TestFuncs which run continuously against production can run under go test
+// in CI.
+//
+//	func TestSmoke(t *testing.T) {
+//		orbitaltest.Run(t, orbital.TestCase{
+//			Name:    "smoke",
+//			Timeout: 10 * time.Second,
+//			Func:    harness.OrbitalSmoke,
+//		})
+//	}
+//
+// Each TestCase runs once, in a subtest named after it.  Everything the
+// TestFunc logs through O goes to t.Log, a failed run fails the subtest, and
+// the TestCase Timeout, or the Service default, is enforced as usual.
+package orbitaltest
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/orbital/orbital"
+)
+
+// Run runs tc, once for every combination of its Params, each in a subtest.
+// Period and Schedule are not needed and default to an hour.
+func Run(t *testing.T, tc orbital.TestCase) {
+	t.Helper()
+	if tc.Period == 0 && tc.Schedule == "" {
+		tc.Period = time.Hour
+	}
+	svc := orbital.New()
+	defer svc.Close()
+	if err := svc.Add(tc); err != nil {
+		t.Fatal(err)
+	}
+	RunService(t, svc)
+}
+
+// RunService runs every TestCase registered with svc once, in registration
+// order, each in a subtest.  svc is prepared if needed, which sets up any
+// harness suites, but not closed.  Disabled TestCases are skipped, as are
+// those whose dependencies failed.
+func RunService(t *testing.T, svc *orbital.Service) {
+	t.Helper()
+	if err := svc.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range svc.TestCases() {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			if tc.Disabled {
+				t.Skip("disabled")
+			}
+			w := &writer{t: t}
+			defer w.close()
+			r, err := svc.RunOnce(context.Background(), tc.Name, w)
+			w.flush()
+			switch {
+			case err != nil:
+				t.Fatal(err)
+			case r.Skipped:
+				t.Skip(r.SkipReason)
+			case r.Failed:
+				t.Fail()
+			}
+		})
+	}
+}
+
+// writer sends what O writes to t.Log, a line at a time.  Once closed it
+// drops writes, so a hung TestFunc which is abandoned can't log to a
+// finished test.
+type writer struct {
+	t *testing.T
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return len(p), nil
+	}
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// Keep the partial line for the next write
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			return len(p), nil
+		}
+		w.t.Log(strings.TrimSuffix(line, "\n"))
+	}
+}
+
+// flush logs any partial line.
+func (w *writer) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed && w.buf.Len() > 0 {
+		w.t.Log(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+func (w *writer) close() {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+}
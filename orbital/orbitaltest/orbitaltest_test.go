@@ -0,0 +1,98 @@
+package orbitaltest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/orbital/orbital"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	var mu sync.Mutex
+	var regions []string
+	Run(t, orbital.TestCase{
+		Name:   "smoke",
+		Params: map[string][]string{"region": {"us-west-2", "eu-west-1"}},
+		Func: func(ctx context.Context, o *orbital.O) {
+			o.Log("checking", o.Param("region"))
+			mu.Lock()
+			regions = append(regions, o.Param("region"))
+			mu.Unlock()
+		},
+	})
+	sort.Strings(regions)
+	assert.Equal(t, []string{"eu-west-1", "us-west-2"}, regions)
+}
+
+func TestRunService(t *testing.T) {
+	var ran []string
+	svc := orbital.New()
+	defer svc.Close()
+	for _, tc := range []orbital.TestCase{
+		{Name: "api"},
+		{Name: "paused", Disabled: true},
+		{Name: "ingest", DependsOn: []string{"api"}},
+	} {
+		tc := tc
+		tc.Period = time.Hour
+		tc.Func = func(ctx context.Context, o *orbital.O) {
+			ran = append(ran, tc.Name)
+		}
+		require.NoError(t, svc.Add(tc))
+	}
+	RunService(t, svc)
+	assert.Equal(t, []string{"api", "ingest"}, ran)
+}
+
+// TestFailing is run by TestFailures in a subprocess, as its subtests are
+// meant to fail.
+func TestFailing(t *testing.T) {
+	if os.Getenv("ORBITALTEST_FAILING") == "" {
+		t.Skip("run by TestFailures")
+	}
+	svc := orbital.New()
+	defer svc.Close()
+	require.NoError(t, svc.Add(orbital.TestCase{
+		Name:   "fails",
+		Period: time.Hour,
+		Func: func(ctx context.Context, o *orbital.O) {
+			o.Errorf("expected %d, got %d", 1, 2)
+		},
+	}))
+	require.NoError(t, svc.Add(orbital.TestCase{
+		Name:    "times_out",
+		Period:  time.Hour,
+		Timeout: 50 * time.Millisecond,
+		Func: func(ctx context.Context, o *orbital.O) {
+			<-ctx.Done()
+		},
+	}))
+	require.NoError(t, svc.Add(orbital.TestCase{
+		Name:      "blocked",
+		Period:    time.Hour,
+		DependsOn: []string{"fails"},
+		Func:      func(ctx context.Context, o *orbital.O) {},
+	}))
+	RunService(t, svc)
+}
+
+func TestFailures(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFailing$", "-test.v")
+	cmd.Env = append(os.Environ(), "ORBITALTEST_FAILING=1")
+	out, err := cmd.CombinedOutput()
+	assert.Error(t, err)
+	s := string(out)
+	assert.Contains(t, s, "--- FAIL: TestFailing/fails")
+	assert.Contains(t, s, "expected 1, got 2")
+	assert.Contains(t, s, "--- FAIL: TestFailing/times_out")
+	assert.Contains(t, s, "failed on context error: context deadline exceeded")
+	assert.Contains(t, s, "--- SKIP: TestFailing/blocked")
+	assert.Contains(t, s, "dependency fails failing")
+}
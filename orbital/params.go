@@ -0,0 +1,69 @@
+package orbital
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/segmentio/stats"
+)
+
+// expandParams returns one TestCase per combination of tc.Params.  Each is
+// named after tc with the parameter values appended in key order, e.g.
+// smoke.us-west-2.1kb, and tagged with the parameters.  A TestCase without
+// Params, or with a parameter which has no values, is returned unchanged.
+// DependsOn smoke refers to all of them, see dependencyNames.
+func expandParams(tc TestCase) []TestCase {
+	if len(tc.Params) == 0 {
+		return []TestCase{tc}
+	}
+	keys := make([]string, 0, len(tc.Params))
+	for k, vs := range tc.Params {
+		if len(vs) == 0 {
+			return []TestCase{tc}
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	combos := []map[string]string{{}}
+	for _, k := range keys {
+		next := make([]map[string]string, 0, len(combos)*len(tc.Params[k]))
+		for _, c := range combos {
+			for _, v := range tc.Params[k] {
+				m := make(map[string]string, len(c)+1)
+				for ck, cv := range c {
+					m[ck] = cv
+				}
+				m[k] = v
+				next = append(next, m)
+			}
+		}
+		combos = next
+	}
+
+	out := make([]TestCase, len(combos))
+	for i, c := range combos {
+		t := tc
+		t.Params = nil
+		t.params = c
+		t.group = tc.Name
+		parts := make([]string, 0, len(keys)+1)
+		parts = append(parts, tc.Name)
+		tags := make([]stats.Tag, 0, len(tc.Tags)+len(keys))
+		tags = append(tags, tc.Tags...)
+		for _, k := range keys {
+			parts = append(parts, c[k])
+			tags = append(tags, stats.T(k, c[k]))
+		}
+		t.Name = strings.Join(parts, ".")
+		t.Tags = tags
+		out[i] = t
+	}
+	return out
+}
+
+// Param returns the value of the named parameter for this run of a TestCase
+// registered with Params, or the empty string.
+func (o *O) Param(key string) string {
+	return o.params[key]
+}
@@ -0,0 +1,83 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParams(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string]string)
+	s := New()
+	s.w = ioutil.Discard
+	require.NoError(t, s.Add(TestCase{
+		Name:   "smoke",
+		Period: time.Hour,
+		Tags:   []stats.Tag{stats.T("team", "core")},
+		Params: map[string][]string{
+			"region": {"us-west-2", "eu-west-1"},
+			"size":   {"1kb", "1mb"},
+		},
+		Func: func(ctx context.Context, o *O) {
+			mu.Lock()
+			seen[o.Param("region")+"/"+o.Param("size")] = o.Param("missing")
+			mu.Unlock()
+		},
+	}))
+
+	var names []string
+	for _, tc := range s.tests {
+		names = append(names, tc.Name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{
+		"smoke.eu-west-1.1kb",
+		"smoke.eu-west-1.1mb",
+		"smoke.us-west-2.1kb",
+		"smoke.us-west-2.1mb",
+	}, names)
+	assert.Equal(t, []stats.Tag{
+		stats.T("team", "core"),
+		stats.T("region", "us-west-2"),
+		stats.T("size", "1kb"),
+	}, s.tests[0].Tags)
+
+	require.NoError(t, s.Run())
+	defer s.Close()
+	for _, tc := range s.tests {
+		s.handle(context.Background(), tc)
+	}
+	assert.Equal(t, map[string]string{
+		"eu-west-1/1kb": "",
+		"eu-west-1/1mb": "",
+		"us-west-2/1kb": "",
+		"us-west-2/1mb": "",
+	}, seen)
+
+	assert.Error(t, s.Add(TestCase{
+		Name:   "smoke",
+		Period: time.Hour,
+		Params: map[string][]string{"region": {"us-west-2"}, "size": {"1kb"}},
+		Func:   func(ctx context.Context, o *O) {},
+	}), "expanded names should not clash")
+	assert.Error(t, s.Add(TestCase{
+		Name:   "empty",
+		Period: time.Hour,
+		Params: map[string][]string{"region": {}},
+		Func:   func(ctx context.Context, o *O) {},
+	}))
+	assert.Error(t, s.Add(TestCase{
+		Name:   "spaces",
+		Period: time.Hour,
+		Params: map[string][]string{"region": {"us west"}},
+		Func:   func(ctx context.Context, o *O) {},
+	}))
+}
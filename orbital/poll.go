@@ -0,0 +1,118 @@
+package orbital
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/segmentio/events"
+)
+
+// Condition is polled by Eventually and Consistently.  It reports whether
+// the condition holds; an error means it doesn't, and is reported if it is
+// the last.
+type Condition func(ctx context.Context) (bool, error)
+
+// defaultPollInterval is used by Eventually and Consistently in place of an
+// interval which isn't positive.
+const defaultPollInterval = 100 * time.Millisecond
+
+// Eventually calls cond every interval until it returns true, and reports
+// whether it did.  If the context of the run, or of the step in progress, is
+// done first, the test fails with the number of attempts and the error from
+// the last attempt, if any.  Each attempt is logged with events.Debug.  An
+// interval which isn't positive is taken as 100ms.
+func (o *O) Eventually(cond Condition, interval time.Duration) bool {
+	ctx := o.context()
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	for attempt := 1; ; attempt++ {
+		ok, err := cond(ctx)
+		o.debugAttempt("Eventually", attempt, ok, err)
+		if ok && err == nil {
+			return true
+		}
+		if !o.wait(ctx, interval) {
+			o.pollFailure("Eventually", fmt.Sprintf("condition not met after %d attempts: %v", attempt, ctx.Err()), err)
+			return false
+		}
+	}
+}
+
+// Consistently calls cond every interval for d, and reports whether it
+// returned true every time.  The test fails as soon as cond returns false or
+// an error, or if the context of the run, or of the step in progress, is done
+// before d has passed.  Each attempt is logged with events.Debug.  An
+// interval which isn't positive is taken as 100ms.
+func (o *O) Consistently(cond Condition, interval, d time.Duration) bool {
+	ctx := o.context()
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	clock := o.timeSource()
+	end := clock.Now().Add(d)
+	for attempt := 1; ; attempt++ {
+		ok, err := cond(ctx)
+		o.debugAttempt("Consistently", attempt, ok, err)
+		if !ok || err != nil {
+			o.pollFailure("Consistently", fmt.Sprintf("condition not met on attempt %d", attempt), err)
+			return false
+		}
+		if !clock.Now().Add(interval).Before(end) {
+			return true
+		}
+		if !o.wait(ctx, interval) {
+			o.pollFailure("Consistently", fmt.Sprintf("stopped after %d attempts: %v", attempt, ctx.Err()), nil)
+			return false
+		}
+	}
+}
+
+// context returns the context of the run, or of the step in progress.
+func (o *O) context() context.Context {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.ctx
+}
+
+// wait waits for d, and reports false if ctx is done first.
+func (o *O) wait(ctx context.Context, d time.Duration) bool {
+	t := o.timeSource().NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (o *O) debugAttempt(check string, attempt int, ok bool, err error) {
+	events.Debug("%{case}s: %{check}s attempt %{attempt}d: %{ok}t %{error}v", o.caseName(), check, attempt, ok, err)
+}
+
+// caseName returns the name of the TestCase being run.
+func (o *O) caseName() string {
+	for _, t := range o.tags {
+		if t.Name == "case" {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// pollFailure fails the test with a Failure for check, called from the
+// caller of Eventually or Consistently.
+func (o *O) pollFailure(check, actual string, lastErr error) {
+	f := Failure{Check: check, Actual: actual}
+	if lastErr != nil {
+		f.Message = "last error: " + lastErr.Error()
+	}
+	if _, file, line, ok := runtime.Caller(2); ok {
+		f.Caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+	o.AddFailure(f)
+}
@@ -0,0 +1,141 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolling(t *testing.T) {
+	tests := []struct {
+		scenario string
+		timeout  time.Duration
+		fn       func(o *O) bool
+		pass     bool
+		// actual is a regexp, as attempt counts depend on timing
+		actual  string
+		message string
+	}{
+		{
+			scenario: "eventually",
+			fn: func(o *O) bool {
+				n := 0
+				return o.Eventually(func(ctx context.Context) (bool, error) {
+					n++
+					if n < 3 {
+						return false, errors.New("not yet")
+					}
+					return true, nil
+				}, time.Millisecond)
+			},
+			pass: true,
+		},
+		{
+			scenario: "eventually times out",
+			timeout:  50 * time.Millisecond,
+			fn: func(o *O) bool {
+				return o.Eventually(func(ctx context.Context) (bool, error) {
+					return false, errors.New("404 not found")
+				}, 20*time.Millisecond)
+			},
+			actual:  `condition not met after \d+ attempts: context deadline exceeded`,
+			message: "last error: 404 not found",
+		},
+		{
+			scenario: "eventually reports the last attempt",
+			timeout:  50 * time.Millisecond,
+			fn: func(o *O) bool {
+				n := 0
+				return o.Eventually(func(ctx context.Context) (bool, error) {
+					n++
+					if n == 1 {
+						return false, errors.New("404 not found")
+					}
+					return false, nil
+				}, time.Millisecond)
+			},
+			actual: `condition not met after \d+ attempts: context deadline exceeded`,
+		},
+		{
+			scenario: "eventually without an interval",
+			timeout:  50 * time.Millisecond,
+			fn: func(o *O) bool {
+				return o.Eventually(func(ctx context.Context) (bool, error) {
+					return false, nil
+				}, 0)
+			},
+			actual: `condition not met after 1 attempts: context deadline exceeded`,
+		},
+		{
+			scenario: "consistently",
+			fn: func(o *O) bool {
+				return o.Consistently(func(ctx context.Context) (bool, error) {
+					return true, nil
+				}, time.Millisecond, 20*time.Millisecond)
+			},
+			pass: true,
+		},
+		{
+			scenario: "consistently fails",
+			fn: func(o *O) bool {
+				n := 0
+				return o.Consistently(func(ctx context.Context) (bool, error) {
+					n++
+					return n < 3, nil
+				}, time.Millisecond, time.Second)
+			},
+			actual: `condition not met on attempt 3`,
+		},
+		{
+			scenario: "consistently errors",
+			fn: func(o *O) bool {
+				return o.Consistently(func(ctx context.Context) (bool, error) {
+					return true, errors.New("500")
+				}, time.Millisecond, time.Second)
+			},
+			actual:  `condition not met on attempt 1`,
+			message: "last error: 500",
+		},
+		{
+			scenario: "consistently outlives the run",
+			timeout:  30 * time.Millisecond,
+			fn: func(o *O) bool {
+				return o.Consistently(func(ctx context.Context) (bool, error) {
+					return true, nil
+				}, 20*time.Millisecond, time.Minute)
+			},
+			actual: `stopped after \d+ attempts: context deadline exceeded`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			s := New(WithOutput(ioutil.Discard))
+			var passed bool
+			run := startCase(t, s, TestCase{
+				Name:    "poll",
+				Period:  time.Hour,
+				Timeout: test.timeout,
+				Func: func(ctx context.Context, o *O) {
+					passed = test.fn(o)
+				},
+			})
+			defer s.Close()
+
+			last := run()
+			assert.Equal(t, test.pass, passed)
+			assert.Equal(t, !test.pass, last.Failed)
+			if test.pass {
+				return
+			}
+			require.Len(t, last.Failures, 1)
+			assert.Regexp(t, "^"+test.actual+"$", last.Failures[0].Actual)
+			assert.Equal(t, test.message, last.Failures[0].Message)
+			assert.Contains(t, last.Failures[0].Caller, "poll_test.go:")
+		})
+	}
+}
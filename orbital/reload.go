@@ -0,0 +1,118 @@
+package orbital
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WithConfigFile makes the Service apply the Config in path on top of the
+// registered TestCases.  The file is reloaded when it changes, or when the
+// process receives SIGHUP, and any TestCases whose schedule changed are
+// rescheduled.  An invalid file fails Run, and is ignored on reload.
+func WithConfigFile(path string) func(*Service) {
+	return func(svc *Service) {
+		svc.configPath = path
+	}
+}
+
+// loadConfig returns the registered TestCases with the config file, if any,
+// applied.  s.mu must be held.
+func (s *Service) loadConfig() ([]TestCase, error) {
+	tests := s.tests
+	if s.configPath != "" {
+		c, err := LoadConfig(s.configPath)
+		if err != nil {
+			return nil, err
+		}
+		if tests, err = c.Apply(tests); err != nil {
+			return nil, err
+		}
+		s.configMod = modTime(s.configPath)
+	}
+	return tests, nil
+}
+
+// Reload rereads the config file and reschedules the TestCases it changes.
+// If the file is invalid, an error is returned and nothing changes.
+func (s *Service) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		return errors.New("service closed")
+	default:
+	}
+	tests, err := s.loadConfig()
+	if err != nil {
+		return err
+	}
+	if !s.started {
+		// Keep a prepared Service, which runs TestCases only through
+		// RunOnce, up to date
+		if s.prepared {
+			s.ready = tests
+		}
+		return nil
+	}
+	for _, tc := range tests {
+		r := s.runners[tc.Name]
+		if r != nil && sameSchedule(r.tc, tc) {
+			continue
+		}
+		if r != nil {
+			// In flight runs of the old runner are left to finish
+			close(r.stop)
+		}
+		s.start(tc)
+	}
+	return nil
+}
+
+// watchConfig reloads the config file on SIGHUP, or when its modification
+// time changes.
+func (s *Service) watchConfig() {
+	defer s.wg.Done()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	poll := s.clock.NewTicker(s.configPoll)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-hup:
+		case <-poll.C():
+			s.mu.Lock()
+			changed := !modTime(s.configPath).Equal(s.configMod)
+			s.mu.Unlock()
+			if !changed {
+				continue
+			}
+		}
+		if err := s.Reload(); err != nil {
+			s.stats.Incr("config.error")
+			fmt.Fprintf(s.w, "config reload: %v\n", err)
+			// Don't retry the same broken file on every poll
+			s.mu.Lock()
+			s.configMod = modTime(s.configPath)
+			s.mu.Unlock()
+			continue
+		}
+		fmt.Fprintf(s.w, "config reloaded from %s\n", s.configPath)
+	}
+}
+
+func modTime(path string) time.Time {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,60 @@
+package orbital
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/segmentio/stats"
+)
+
+// ReportMetric reports a custom numeric result of the run in the given unit,
+// such as events_lost or bytes.  Like testing.B.ReportMetric, reporting the
+// same unit twice overwrites the first value.  Reported values are set on the
+// "reported" gauge tagged with the case and unit, attached to the Result and
+// printed on the PASS/FAIL line.  If the TestCase has a Limit for unit, the
+// run fails when the value exceeds it.
+func (o *O) ReportMetric(value float64, unit string) {
+	if unit == "" || strings.IndexFunc(unit, unicode.IsSpace) >= 0 {
+		o.Errorf("ReportMetric: invalid unit %q", unit)
+		return
+	}
+	o.mu.Lock()
+	if o.reported == nil {
+		o.reported = make(map[string]float64)
+	}
+	o.reported[unit] = value
+	o.mu.Unlock()
+	o.stats.Set("reported", value, append([]stats.Tag{stats.T("unit", unit)}, o.tags...)...)
+}
+
+// checkLimits fails the run for every reported metric over its limit.
+func (o *O) checkLimits(limits map[string]float64) {
+	o.mu.Lock()
+	var over []string
+	for unit, max := range limits {
+		if v, ok := o.reported[unit]; ok && v > max {
+			over = append(over, fmt.Sprintf("%s = %g exceeds limit %g", unit, v, max))
+		}
+	}
+	o.mu.Unlock()
+	sort.Strings(over)
+	for _, msg := range over {
+		o.Error(msg)
+	}
+}
+
+// formatReported formats reported metrics as testing.B does, sorted by unit.
+func formatReported(reported map[string]float64) string {
+	units := make([]string, 0, len(reported))
+	for u := range reported {
+		units = append(units, u)
+	}
+	sort.Strings(units)
+	var b strings.Builder
+	for _, u := range units {
+		fmt.Fprintf(&b, " %g %s", reported[u], u)
+	}
+	return b.String()
+}
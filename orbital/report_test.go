@@ -0,0 +1,47 @@
+package orbital
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/segmentio/stats"
+	"github.com/segmentio/stats/statstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportMetric(t *testing.T) {
+	h := &statstest.Handler{}
+	var out bytes.Buffer
+	s := New(WithStats(stats.NewEngine("", h)), WithOutput(&out))
+	lost := 0.0
+	run := startCase(t, s, TestCase{
+		Name:   "report_test",
+		Period: time.Hour,
+		Limits: map[string]float64{"events_lost": 2},
+		Func: func(ctx context.Context, o *O) {
+			o.ReportMetric(1, "events_lost")
+			o.ReportMetric(lost, "events_lost")
+			o.ReportMetric(4096, "bytes")
+		},
+	})
+	defer s.Close()
+
+	last := run()
+	assert.False(t, last.Failed)
+	assert.Equal(t, map[string]float64{"events_lost": 0, "bytes": 4096}, last.Reported)
+	assert.Contains(t, out.String(), ") 4096 bytes 0 events_lost\n")
+
+	reported := measures(h, "reported")
+	require.Len(t, reported, 3)
+	assert.Equal(t, "bytes", tagValue(reported[2].Tags, "unit"))
+	assert.Equal(t, "report_test", tagValue(reported[2].Tags, "case"))
+
+	lost = 3
+	last = run()
+	assert.True(t, last.Failed)
+	assert.Equal(t, []string{"events_lost = 3 exceeds limit 2"}, last.Errors)
+	assert.Contains(t, out.String(), "--- FAIL: report_test (")
+}
@@ -0,0 +1,76 @@
+package orbital
+
+import (
+	"time"
+
+	"github.com/segmentio/stats"
+)
+
+// Result is the outcome of a single run of a TestCase.
+type Result struct {
+	// ID uniquely identifies the run.
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Tags     []stats.Tag   `json:"tags,omitempty"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Failed   bool          `json:"failed"`
+	// Hung runs are failed runs whose TestFunc did not return in time and
+	// was abandoned.
+	Hung bool `json:"hung,omitempty"`
+	// Attempts is the number of times the TestFunc was run, including
+	// retries.
+	Attempts int `json:"attempts,omitempty"`
+	// Skipped runs never call the TestFunc.  SkipReason says why.
+	Skipped    bool   `json:"skipped,omitempty"`
+	SkipReason string `json:"skip_reason,omitempty"`
+	// Output is everything the test logged through O during the run.
+	Output string `json:"output,omitempty"`
+	// Errors holds the messages passed to O.Error and O.Errorf.
+	Errors []string `json:"errors,omitempty"`
+	// Failures holds the structured failures passed to O.AddFailure, such
+	// as those of the orbital/check package.
+	Failures []Failure `json:"failures,omitempty"`
+	// Metrics holds the values recorded through O.Observe, O.Incr, O.Set
+	// and O.Clock.
+	Metrics []Metric `json:"metrics,omitempty"`
+	// Steps holds the phases run with O.Step, and FailedStep names the
+	// first of them to fail.
+	Steps      []StepResult `json:"steps,omitempty"`
+	FailedStep string       `json:"failed_step,omitempty"`
+	// Reported holds the values passed to O.ReportMetric, by unit.
+	Reported map[string]float64 `json:"reported,omitempty"`
+	// LeakedGoroutines is the number of goroutines the run left running.
+	// It is only set with WithLeakDetection.
+	LeakedGoroutines int `json:"leaked_goroutines,omitempty"`
+	// TraceID identifies the trace of the run.  See SpanExporter.
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+func (o *O) result(tc TestCase, start time.Time, dur time.Duration) Result {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var reported map[string]float64
+	if len(o.reported) > 0 {
+		reported = make(map[string]float64, len(o.reported))
+		for u, v := range o.reported {
+			reported[u] = v
+		}
+	}
+	return Result{
+		ID:               o.id,
+		Name:             tc.Name,
+		Tags:             tc.Tags,
+		Start:            start,
+		Duration:         dur,
+		Failed:           o.failed,
+		Output:           o.out.String(),
+		Errors:           append([]string(nil), o.errors...),
+		Failures:         append([]Failure(nil), o.failures...),
+		Metrics:          append([]Metric(nil), o.metrics...),
+		Steps:            append([]StepResult(nil), o.steps...),
+		FailedStep:       o.failedStep,
+		Reported:         reported,
+		LeakedGoroutines: o.leaked,
+	}
+}
@@ -0,0 +1,195 @@
+package orbital
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Schedule is a parsed cron expression.  See ParseSchedule.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// whether the day of month and day of week fields start with *, which
+	// makes them unrestricted for the purpose of matching a day
+	domStar, dowStar bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// ParseSchedule parses a standard five field cron expression: minute, hour,
+// day of month, month and day of week.  Each field may be *, a number, a
+// range a-b, or a comma separated list of those, optionally followed by a
+// step /n.  As in cron, when both day fields are restricted a time matching
+// either of them matches.
+func ParseSchedule(expr string) (Schedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return Schedule{}, errors.Errorf("schedule %q: expected %d fields, got %d", expr, len(cronFields), len(parts))
+	}
+	var bits [5]uint64
+	for i, p := range parts {
+		b, err := parseCronField(p, cronFields[i])
+		if err != nil {
+			return Schedule{}, errors.Wrapf(err, "schedule %q", expr)
+		}
+		bits[i] = b
+	}
+	// Sunday may be written as 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return Schedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: strings.HasPrefix(parts[2], "*"),
+		dowStar: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func parseCronField(s string, f cronField) (uint64, error) {
+	max := f.max
+	if f.name == "day of week" {
+		max = 7
+	}
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		step := 1
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, errors.Errorf("invalid step in %s field %q", f.name, item)
+			}
+			step = n
+			item = item[:i]
+		}
+		lo, hi := f.min, max
+		switch {
+		case item == "*":
+		case strings.IndexByte(item, '-') > 0:
+			i := strings.IndexByte(item, '-')
+			var err error
+			if lo, err = strconv.Atoi(item[:i]); err != nil {
+				return 0, errors.Errorf("invalid %s %q", f.name, item)
+			}
+			if hi, err = strconv.Atoi(item[i+1:]); err != nil {
+				return 0, errors.Errorf("invalid %s %q", f.name, item)
+			}
+		default:
+			n, err := strconv.Atoi(item)
+			if err != nil {
+				return 0, errors.Errorf("invalid %s %q", f.name, item)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < f.min || hi > max || lo > hi {
+			return 0, errors.Errorf("%s %q out of range %d-%d", f.name, item, f.min, f.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// checkSchedule parses expr, failing if it is invalid or, like 0 0 31 2 *,
+// never matches.
+func checkSchedule(expr string) error {
+	sched, err := ParseSchedule(expr)
+	if err != nil {
+		return err
+	}
+	if sched.Next(time.Now()).IsZero() {
+		return errors.Errorf("schedule %q never matches", expr)
+	}
+	return nil
+}
+
+// Next returns the first time after t matching the schedule, or the zero
+// time if there is none within five years.
+func (s Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s Schedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// ticker returns a channel delivering the times on clock at which tc should
+// run, and a function to stop it.
+func ticker(clock TimeSource, tc TestCase) (<-chan time.Time, func()) {
+	if tc.Schedule == "" {
+		t := clock.NewTicker(tc.Period)
+		return t.C(), t.Stop
+	}
+	// Schedules are validated before test cases are started
+	sched, _ := ParseSchedule(tc.Schedule)
+	c := make(chan time.Time, 1)
+	stop := make(chan struct{})
+	go func() {
+		for {
+			now := clock.Now()
+			next := sched.Next(now)
+			if next.IsZero() {
+				return
+			}
+			t := clock.NewTimer(next.Sub(now))
+			select {
+			case now := <-t.C():
+				// Like time.Ticker, drop ticks for slow receivers
+				select {
+				case c <- now:
+				default:
+				}
+			case <-stop:
+				t.Stop()
+				return
+			}
+		}
+	}()
+	return c, func() { close(stop) }
+}
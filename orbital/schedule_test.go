@@ -0,0 +1,58 @@
+package orbital
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2018, time.May, 16, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2018, time.May, 16, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2018, time.May, 16, 10, 15, 0, 0, time.UTC)},
+		{"5 * * * *", time.Date(2018, time.May, 16, 11, 5, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2018, time.May, 17, 3, 0, 0, 0, time.UTC)},
+		{"30 9-17/4 * * *", time.Date(2018, time.May, 16, 13, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2018, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2018, time.May, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2018, time.May, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * 2 1,5", time.Date(2019, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		// Either day field may match when both are restricted
+		{"0 0 31 * 5", time.Date(2018, time.May, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			s, err := ParseSchedule(test.expr)
+			require.NoError(t, err)
+			assert.Equal(t, test.next, s.Next(from))
+		})
+	}
+}
+
+func TestScheduleErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	} {
+		_, err := ParseSchedule(expr)
+		assert.Error(t, err, "%q should not parse", expr)
+	}
+}
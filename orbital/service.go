@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/pkg/errors"
+	"github.com/segmentio/ksuid"
 	"github.com/segmentio/stats"
 )
 
@@ -16,12 +20,45 @@ import (
 type Service struct {
 	// list of tests to run
 	tests   []TestCase
+	envs    []Environment
 	stats   *stats.Engine
 	mu      sync.Mutex
 	started bool
 
 	defaultTimeout time.Duration
 
+	coordinator Coordinator
+
+	notifiers    []Notifier
+	failAfter    int
+	recoverAfter int
+	// per test case runtime state, keyed by name
+	cases map[string]*caseState
+	// history of every Result
+	results ResultStore
+	// receives the spans of runs, if set
+	exporter SpanExporter
+	// values given to RegisterHarness, for suite setup and teardown
+	harnesses []interface{}
+	// set by Prepare, with the TestCases as prepared
+	prepared bool
+	ready    []TestCase
+	// scheduled test cases, keyed by name
+	runners map[string]*runner
+	loads   []*loadState
+
+	// how long past its timeout a run may go before it is abandoned
+	hangGrace time.Duration
+	clock     TimeSource
+
+	leakMode   LeakMode
+	leakIgnore []string
+
+	configPath string
+	configPoll time.Duration
+	// modification time of the config file when it was last loaded
+	configMod time.Time
+
 	w io.Writer
 
 	done chan struct{}
@@ -29,6 +66,14 @@ type Service struct {
 	wg   sync.WaitGroup
 }
 
+// WithOutput makes the Service write the output of runs, and the lines
+// reporting them, to w rather than os.Stderr.
+func WithOutput(w io.Writer) func(*Service) {
+	return func(svc *Service) {
+		svc.w = w
+	}
+}
+
 func WithStats(s *stats.Engine) func(*Service) {
 	return func(svc *Service) {
 		svc.stats = s
@@ -41,12 +86,46 @@ func WithTimeout(d time.Duration) func(*Service) {
 	}
 }
 
+// WithCoordinator makes the Service consult c before each run, so that
+// replicas of the Service share the work instead of all running every
+// TestCase.
+func WithCoordinator(c Coordinator) func(*Service) {
+	return func(svc *Service) {
+		svc.coordinator = c
+	}
+}
+
+// WithNotifier adds a Notifier to be told about alert transitions.  It may be
+// given more than once.
+func WithNotifier(n Notifier) func(*Service) {
+	return func(svc *Service) {
+		svc.notifiers = append(svc.notifiers, n)
+	}
+}
+
+// WithAlertThresholds sets the default number of consecutive failures before
+// a TestCase alerts, and consecutive passes before it recovers.
+func WithAlertThresholds(failAfter, recoverAfter int) func(*Service) {
+	return func(svc *Service) {
+		svc.failAfter = failAfter
+		svc.recoverAfter = recoverAfter
+	}
+}
+
 func New(opts ...func(*Service)) *Service {
 	s := &Service{
 		w:              os.Stderr,
 		done:           make(chan struct{}),
 		tests:          make([]TestCase, 0),
+		cases:          make(map[string]*caseState),
+		runners:        make(map[string]*runner),
+		configPoll:     5 * time.Second,
+		hangGrace:      30 * time.Second,
 		defaultTimeout: 10 * time.Minute,
+		failAfter:      1,
+		recoverAfter:   1,
+		results:        NewMemoryStore(100),
+		clock:          SystemClock,
 	}
 	for _, o := range opts {
 		o(s)
@@ -60,75 +139,345 @@ func Register(tc TestCase) {
 	DefaultService.Register(tc)
 }
 
-// Register a test case to be run.
+func Add(tc TestCase) error {
+	return DefaultService.Add(tc)
+}
+
+// Register a test case to be run.  Problems with tc are only reported by
+// Validate and Run; use Add to catch them at registration.
 func (s *Service) Register(tc TestCase) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.tests = append(s.tests, tc)
+	s.register(tc)
+}
+
+// register adds tc, expanding its Params and Environments.  s.mu must be
+// held.
+func (s *Service) register(tc TestCase) {
+	for _, t := range s.expand(tc) {
+		s.tests = append(s.tests, t)
+		if _, ok := s.cases[t.Name]; !ok {
+			cs := &caseState{}
+			if t.SLO != nil {
+				cs.slo = newSLOState(*t.SLO)
+			}
+			s.cases[t.Name] = cs
+		}
+	}
+}
+
+// Run starts running all registered TestCases.  An error is returned, and
+// nothing is started, if the TestCases are invalid (see Validate), if the
+// config file is invalid, or if a harness fails to set up.
+func (s *Service) Run() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return nil
+	}
+	tests, err := s.prepare()
+	if err != nil {
+		return err
+	}
+	for _, tc := range tests {
+		s.start(tc)
+	}
+	for _, l := range s.loads {
+		s.wg.Add(1)
+		go s.runLoad(l)
+	}
+	if s.configPath != "" {
+		s.wg.Add(1)
+		go s.watchConfig()
+	}
+	s.started = true
+	return nil
 }
 
-func (s *Service) Run() {
+// Prepare does everything Run does except scheduling the TestCases: it
+// validates them, loads the config file, restores state from the ResultStore
+// and sets up harness suites.  The TestCases can then be run on demand with
+// RunOnce.  Close tears the suites down.  Run calls Prepare itself.
+func (s *Service) Prepare() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	_, err := s.prepare()
+	return err
+}
+
+// prepare implements Prepare, returning the TestCases with any config
+// applied.  s.mu must be held.
+func (s *Service) prepare() ([]TestCase, error) {
 	if s.stats == nil {
 		s.stats = stats.DefaultEngine
 	}
+	if s.prepared {
+		return s.ready, nil
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	tests, err := s.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	s.replay(tests)
+	if err := s.setupSuites(); err != nil {
+		return nil, err
+	}
+	s.prepared, s.ready = true, tests
+	return tests, nil
+}
+
+// TestCases returns the registered TestCases, expanded from Params and
+// Environments.  Once the Service is prepared, they have any config applied,
+// and once it is running they reflect config reloads.
+func (s *Service) TestCases() []TestCase {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.prepared && !s.started {
+		return append([]TestCase(nil), s.ready...)
+	}
+	return s.scheduled()
+}
+
+// RunOnce runs the named TestCase once, as a scheduled run would, and
+// returns its Result.  The run is retried, reported and recorded as usual,
+// but its output, and the lines reporting it, are written to w rather than
+// the Service output if w is not nil.  The TestCase is run even if it is
+// disabled, but is skipped while a dependency is failing.  The Service is
+// prepared first if needed; an error is returned if that fails, if there is
+// no such TestCase, or if it is already running.  Scheduled runs of the
+// TestCase are dropped while RunOnce runs it.
+func (s *Service) RunOnce(ctx context.Context, name string, w io.Writer) (Result, error) {
+	s.mu.Lock()
+	_, err := s.prepare()
+	tests := s.scheduled()
 	if !s.started {
-		for _, v := range s.tests {
-			s.wg.Add(1)
-			go s.run(v)
+		tests = s.ready
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return Result{}, err
+	}
+	if w == nil {
+		w = s.w
+	}
+	for _, tc := range tests {
+		if tc.Name != name {
+			continue
+		}
+		s.mu.Lock()
+		cs := s.cases[name]
+		s.mu.Unlock()
+		if cs != nil {
+			if !cs.begin(true) {
+				return Result{}, errors.Errorf("test case %s is already running", name)
+			}
+			defer cs.end(true)
+		}
+		if reason := s.blocked(tc); reason != "" {
+			return s.skip(w, tc, reason), nil
 		}
+		return s.execute(ctx, tc, w), nil
 	}
-	s.started = true
+	return Result{}, errors.Errorf("no test case named %s", name)
+}
+
+// start runs tc in a new runner.  s.mu must be held.
+func (s *Service) start(tc TestCase) {
+	r := &runner{
+		tc:   tc,
+		stop: make(chan struct{}),
+	}
+	s.runners[tc.Name] = r
+	s.wg.Add(1)
+	go s.run(r)
 }
 
+// handle runs tc, retrying failures up to tc.Retries times, then reports
+// and records the final Result.  Nothing is run while RunOnce is running tc.
 func (s *Service) handle(ctx context.Context, tc TestCase) {
-	start := time.Now()
+	s.mu.Lock()
+	cs := s.cases[tc.Name]
+	s.mu.Unlock()
+	if cs != nil {
+		if !cs.begin(false) {
+			return
+		}
+		defer cs.end(false)
+	}
+	s.execute(ctx, tc, s.w)
+}
+
+// execute implements handle, writing output to w, and returns the Result.
+func (s *Service) execute(ctx context.Context, tc TestCase, w io.Writer) Result {
+	ctx, span := startSpan(ctx, tc.Name)
+	var r Result
+	for attempt := 1; ; attempt++ {
+		r = s.attempt(ctx, tc, w)
+		r.Attempts = attempt
+		if !r.Failed || r.Hung || attempt > tc.Retries || ctx.Err() != nil {
+			break
+		}
+		fmt.Fprintf(w, "--- RETRY: %s (attempt %d of %d)\n", tc.Name, attempt+1, tc.Retries+1)
+	}
+	r.TraceID = span.Context.TraceID.String()
+	span.Attributes = map[string]string{
+		"run.id":   r.ID,
+		"attempts": strconv.Itoa(r.Attempts),
+	}
+	for _, t := range tc.Tags {
+		span.Attributes[t.Name] = t.Value
+	}
+	endSpan(s.exporter, span, r.Failed)
+	s.report(w, tc, r)
+	s.record(tc, r)
+	return r
+}
+
+// attempt runs tc once.
+func (s *Service) attempt(ctx context.Context, tc TestCase, w io.Writer) Result {
+	start := s.clock.Now()
 	o := &O{
-		w:     s.w,
-		stats: s.stats,
+		w:        w,
+		id:       ksuid.New().String(),
+		stats:    s.stats,
+		clock:    s.clock,
+		exporter: s.exporter,
+		tags:     append([]stats.Tag{stats.T("case", tc.Name)}, tc.Tags...),
+		params:   tc.params,
+		env:      tc.env,
 	}
 	to := s.defaultTimeout
 	if tc.Timeout > 10*time.Millisecond {
 		to = tc.Timeout
 	}
-	c, cancel := context.WithTimeout(ctx, to)
+	c, cancel := withTimeout(ctx, s.clock, to)
 	defer cancel()
-	tc.Func(c, o)
-	if c.Err() != nil && !o.failed {
+	o.ctx = c
+	if s.call(c, o, tc, to) {
+		o.dumpHTTP()
+		r := o.result(tc, start, s.clock.Now().Sub(start))
+		r.Hung = true
+		return r
+	}
+	// Measured before the leak check, whose wait is not part of the run
+	dur := s.clock.Now().Sub(start)
+	if c.Err() != nil && !o.Failed() {
 		o.Errorf("failed on context error: %v", c.Err())
 	}
-	dur := time.Now().Sub(start)
-	if o.failed {
+	s.checkLeaks(o, tc)
+	o.checkLimits(tc.Limits)
+	if o.Failed() {
+		o.dumpHTTP()
+	}
+	return o.result(tc, start, dur)
+}
+
+// report emits the metrics and output for the final Result of a run.
+func (s *Service) report(w io.Writer, tc TestCase, r Result) {
+	if r.Failed {
 		tags := append([]stats.Tag{
 			stats.T("case", tc.Name),
 			stats.T("result", "fail"),
 		}, tc.Tags...)
-		s.stats.Observe("case", dur, tags...)
-		fmt.Fprintf(s.w, "--- FAIL: %s (%s)\n", tc.Name, dur)
+		s.stats.Observe("case", r.Duration, tags...)
+		verdict := "FAIL"
+		if r.Hung {
+			verdict = "HUNG"
+		}
+		fmt.Fprintf(w, "--- %s: %s (%s)%s\n", verdict, tc.Name, r.Duration, formatReported(r.Reported))
 	} else {
 		tags := append([]stats.Tag{
 			stats.T("case", tc.Name),
 			stats.T("result", "pass"),
 		}, tc.Tags...)
-		s.stats.Observe("case", dur, tags...)
-		fmt.Fprintf(s.w, "--- PASS: %s (%s)\n", tc.Name, dur)
+		s.stats.Observe("case", r.Duration, tags...)
+		fmt.Fprintf(w, "--- PASS: %s (%s)%s\n", tc.Name, r.Duration, formatReported(r.Reported))
+	}
+}
+
+// record updates the runtime state of tc with the result of a run.
+func (s *Service) record(tc TestCase, r Result) {
+	s.mu.Lock()
+	cs := s.cases[tc.Name]
+	s.mu.Unlock()
+	if cs == nil {
+		return
+	}
+	cs.setLast(r)
+	s.store(r)
+	if r.Skipped {
+		return
+	}
+	failAfter, recoverAfter := s.thresholds(tc)
+	if a, ok := cs.alert.record(tc, r, failAfter, recoverAfter); ok {
+		s.notify(a)
+	}
+	s.recordSLO(cs, tc, r)
+}
+
+// thresholds returns the FailAfter and RecoverAfter of tc, or the Service
+// defaults.
+func (s *Service) thresholds(tc TestCase) (failAfter, recoverAfter int) {
+	failAfter, recoverAfter = s.failAfter, s.recoverAfter
+	if tc.FailAfter > 0 {
+		failAfter = tc.FailAfter
+	}
+	if tc.RecoverAfter > 0 {
+		recoverAfter = tc.RecoverAfter
+	}
+	return failAfter, recoverAfter
+}
+
+func (s *Service) notify(a Alert) {
+	for _, n := range s.notifiers {
+		ctx, cancel := context.WithTimeout(context.Background(), s.defaultTimeout)
+		err := n.Notify(ctx, a)
+		cancel()
+		if err != nil {
+			s.stats.Incr("notify.error", stats.T("case", a.Case.Name))
+			fmt.Fprintf(s.w, "notify %s (%s): %v\n", a.Case.Name, a.Status, err)
+		}
 	}
 }
 
-func (s *Service) run(tc TestCase) {
-	tick := time.NewTicker(tc.Period)
+// runner schedules the runs of a single TestCase.  It is replaced when a
+// config reload changes the TestCase.
+type runner struct {
+	tc   TestCase
+	stop chan struct{}
+}
+
+func (s *Service) run(r *runner) {
+	tc := r.tc
+	next, stop := ticker(s.clock, tc)
 	// Waitgroup for different invocations of this test case
 	var wg sync.WaitGroup
 
 loop:
 	for {
 		select {
-		case <-tick.C:
+		case <-next:
+		case <-r.stop:
+			break loop
 		case <-s.done:
-			tick.Stop()
 			break loop
 		}
+		// Only the replica which owns tc reports its health, so that the
+		// others don't report it as never run
+		if !s.shouldRun(tc) {
+			continue
+		}
+		s.reportHealth(tc)
+		if tc.Disabled {
+			continue
+		}
+		if reason := s.blocked(tc); reason != "" {
+			s.skipScheduled(tc, reason)
+			continue
+		}
 		ctx, cancel := context.WithCancel(context.Background())
 		complete := make(chan struct{})
 		wg.Add(1)
@@ -149,14 +498,106 @@ loop:
 			}
 		}(complete, cancel)
 	}
+	stop()
 	wg.Wait()
 	s.wg.Done()
 }
 
+// shouldRun asks the Coordinator, if any, whether this replica runs tc.
+func (s *Service) shouldRun(tc TestCase) bool {
+	if s.coordinator == nil {
+		return true
+	}
+	ok, err := s.coordinator.ShouldRun(tc.Name)
+	if err != nil {
+		s.stats.Incr("coordinator.error", stats.T("case", tc.Name))
+		fmt.Fprintf(s.w, "coordinator %s: %v\n", tc.Name, err)
+		return false
+	}
+	return ok
+}
+
+// Close stops all TestCases, waits for them to return, tears down any
+// harnesses and closes the ResultStore.
 func (s *Service) Close() error {
+	var err error
 	s.once.Do(func() {
 		close(s.done)
+		s.wg.Wait()
+		s.mu.Lock()
+		prepared := s.prepared
+		s.mu.Unlock()
+		if prepared {
+			err = teardownSuites(s.harnesses)
+		}
+		if cerr := s.results.Close(); err == nil {
+			err = cerr
+		}
 	})
 	s.wg.Wait()
-	return nil
+	return err
+}
+
+// caseState holds what the Service knows about a TestCase between runs.
+type caseState struct {
+	// runs in progress, first for 64-bit alignment
+	running int64
+
+	alert alertState
+	// nil unless the TestCase has an SLO
+	slo *sloState
+
+	mu   sync.Mutex
+	last *Result
+	// set while RunOnce runs the TestCase
+	manual bool
+	// start of the last run, and of the last passing run, skips excluded
+	lastRun, lastSuccess time.Time
+}
+
+// begin counts a run starting, and reports whether it may.  Runs may
+// overlap, except with one made by RunOnce, which is manual.
+func (cs *caseState) begin(manual bool) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.manual || (manual && atomic.LoadInt64(&cs.running) > 0) {
+		return false
+	}
+	atomic.AddInt64(&cs.running, 1)
+	if manual {
+		cs.manual = true
+	}
+	return true
+}
+
+// end counts a run started by begin finishing.
+func (cs *caseState) end(manual bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	atomic.AddInt64(&cs.running, -1)
+	if manual {
+		cs.manual = false
+	}
+}
+
+func (cs *caseState) setLast(r Result) {
+	cs.mu.Lock()
+	cs.last = &r
+	if !r.Skipped {
+		cs.lastRun = r.Start
+		if !r.Failed {
+			cs.lastSuccess = r.Start
+		}
+	}
+	cs.mu.Unlock()
+}
+
+// lastResult returns the most recent Result, if there is one.
+func (cs *caseState) lastResult() (Result, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.last == nil {
+		return Result{}, false
+	}
+	return *cs.last, true
 }
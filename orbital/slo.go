@@ -0,0 +1,208 @@
+package orbital
+
+import (
+	"sync"
+	"time"
+
+	"github.com/segmentio/stats"
+)
+
+// SLO is a service level objective for a TestCase: the ratio of runs which
+// should pass over a rolling window, e.g. 99.9% over 30 days.
+type SLO struct {
+	// Target is the ratio of runs which should pass, between 0 and 1
+	// exclusive.
+	Target float64
+	// Window is the rolling period over which compliance is measured.
+	Window time.Duration
+}
+
+// SLOStatus is a snapshot of the compliance of a TestCase with its SLO.
+type SLOStatus struct {
+	Target   float64       `json:"target"`
+	Window   time.Duration `json:"window"`
+	Runs     int64         `json:"runs"`
+	Failures int64         `json:"failures"`
+	// Compliance is the ratio of runs which passed over the window.
+	Compliance float64 `json:"compliance"`
+	// BudgetRemaining is the fraction of the error budget left over the
+	// window.  It is negative once the budget is exhausted.
+	BudgetRemaining float64 `json:"budget_remaining"`
+	// BurnRates is the rate at which the error budget is being spent over
+	// each of the short windows "5m", "1h" and "6h".  A burn rate of 1
+	// spends exactly the budget over the window.
+	BurnRates map[string]float64 `json:"burn_rates"`
+	// FastBurn is set when both the 5m and 1h burn rates exceed
+	// FastBurnRate.
+	FastBurn bool `json:"fast_burn"`
+}
+
+// FastBurnRate is the burn rate above which the budget is burning fast: at
+// this rate, 2% of a 30 day budget is spent in an hour.
+const FastBurnRate = 14.4
+
+// burnWindows are the windows over which burn rates are reported.
+var burnWindows = []struct {
+	name string
+	d    time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+}
+
+// sloBucket is the resolution at which runs are counted over the burn
+// windows, and over SLO windows no longer than the longest of them.
+const sloBucket = time.Minute
+
+// sloMaxBuckets caps the buckets counting runs over a longer SLO window,
+// which are made coarser to fit: a 30 day window is counted in hours.
+const sloMaxBuckets = 720
+
+type sloCount struct {
+	bucket int64
+	runs   int64
+	failed int64
+}
+
+// sloRing counts runs in buckets of size covering a span of time.
+type sloRing struct {
+	size    time.Duration
+	buckets []sloCount
+}
+
+func newSLORing(span, size time.Duration) *sloRing {
+	return &sloRing{
+		size:    size,
+		buckets: make([]sloCount, int((span+size-1)/size)),
+	}
+}
+
+func (r *sloRing) add(t time.Time, failed bool) {
+	n := t.UnixNano() / int64(r.size)
+	c := &r.buckets[n%int64(len(r.buckets))]
+	if c.bucket != n {
+		*c = sloCount{bucket: n}
+	}
+	c.runs++
+	if failed {
+		c.failed++
+	}
+}
+
+// count returns the runs and failures in the window d ending at t.
+func (r *sloRing) count(t time.Time, d time.Duration) (runs, failed int64) {
+	n := t.UnixNano() / int64(r.size)
+	span := int64((d + r.size - 1) / r.size)
+	for _, c := range r.buckets {
+		if c.bucket <= n && n-c.bucket < span {
+			runs += c.runs
+			failed += c.failed
+		}
+	}
+	return runs, failed
+}
+
+// sloState counts the runs of a TestCase in per minute buckets covering the
+// burn windows and, if it is longer, in at most sloMaxBuckets coarser ones
+// covering the SLO window.
+type sloState struct {
+	slo SLO
+
+	mu sync.Mutex
+	// fine covers the burn windows, and window the SLO window if it is
+	// longer than they are
+	fine, window *sloRing
+	burning      bool
+}
+
+func newSLOState(slo SLO) *sloState {
+	last := burnWindows[len(burnWindows)-1].d
+	ss := &sloState{
+		slo:  slo,
+		fine: newSLORing(last, sloBucket),
+	}
+	ss.window = ss.fine
+	if slo.Window > last {
+		size := slo.Window / sloMaxBuckets
+		// Whole minutes, so that buckets line up with the fine ones
+		size = (size + sloBucket - 1) / sloBucket * sloBucket
+		ss.window = newSLORing(slo.Window, size)
+	}
+	return ss
+}
+
+// record counts a run ending at t.  It returns the resulting status, and
+// whether the budget has just started burning fast.
+func (ss *sloState) record(t time.Time, failed bool) (SLOStatus, bool) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.fine.add(t, failed)
+	if ss.window != ss.fine {
+		ss.window.add(t, failed)
+	}
+	st := ss.status(t)
+	started := st.FastBurn && !ss.burning
+	ss.burning = st.FastBurn
+	return st, started
+}
+
+// current returns the status as of t.
+func (ss *sloState) current(t time.Time) SLOStatus {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.status(t)
+}
+
+// status returns the status as of t.  ss.mu must be held.
+func (ss *sloState) status(t time.Time) SLOStatus {
+	budget := 1 - ss.slo.Target
+	st := SLOStatus{
+		Target:          ss.slo.Target,
+		Window:          ss.slo.Window,
+		Compliance:      1,
+		BudgetRemaining: 1,
+		BurnRates:       make(map[string]float64, len(burnWindows)),
+	}
+	st.Runs, st.Failures = ss.window.count(t, ss.slo.Window)
+	if st.Runs > 0 {
+		ratio := float64(st.Failures) / float64(st.Runs)
+		st.Compliance = 1 - ratio
+		st.BudgetRemaining = 1 - ratio/budget
+	}
+	for _, w := range burnWindows {
+		if runs, failed := ss.fine.count(t, w.d); runs > 0 {
+			st.BurnRates[w.name] = float64(failed) / float64(runs) / budget
+		} else {
+			st.BurnRates[w.name] = 0
+		}
+	}
+	st.FastBurn = st.BurnRates["5m"] > FastBurnRate && st.BurnRates["1h"] > FastBurnRate
+	return st
+}
+
+// recordSLO feeds r into the SLO of tc, reports the SLO gauges and notifies
+// when the error budget starts burning fast.
+func (s *Service) recordSLO(cs *caseState, tc TestCase, r Result) {
+	if cs.slo == nil {
+		return
+	}
+	st, started := cs.slo.record(r.Start.Add(r.Duration), r.Failed)
+
+	tags := append([]stats.Tag{
+		stats.T("case", tc.Name),
+	}, tc.Tags...)
+	s.stats.Set("slo.compliance", st.Compliance, tags...)
+	s.stats.Set("slo.budget_remaining", st.BudgetRemaining, tags...)
+	for _, w := range burnWindows {
+		s.stats.Set("slo.burn_rate", st.BurnRates[w.name], append(tags, stats.T("window", w.name))...)
+	}
+	if started {
+		s.notify(Alert{
+			Status: StatusBudgetBurn,
+			Case:   tc,
+			Result: r,
+			SLO:    &st,
+		})
+	}
+}
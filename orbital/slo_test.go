@@ -0,0 +1,148 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/segmentio/stats"
+	"github.com/segmentio/stats/statstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLOState(t *testing.T) {
+	ss := newSLOState(SLO{Target: 0.99, Window: 24 * time.Hour})
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 100 passes spread over the first 100 minutes
+	for i := 0; i < 100; i++ {
+		_, burning := ss.record(start.Add(time.Duration(i)*time.Minute), false)
+		assert.False(t, burning)
+	}
+	st := ss.current(start.Add(100 * time.Minute))
+	assert.Equal(t, int64(100), st.Runs)
+	assert.Equal(t, 1.0, st.Compliance)
+	assert.Equal(t, 1.0, st.BudgetRemaining)
+	assert.Equal(t, 0.0, st.BurnRates["1h"])
+
+	// A burst of failures burns the budget fast, but only notifies once
+	now := start.Add(100 * time.Minute)
+	notified := 0
+	for i := 0; i < 20; i++ {
+		if _, burning := ss.record(now, true); burning {
+			notified++
+		}
+	}
+	assert.Equal(t, 1, notified)
+
+	st = ss.current(now)
+	assert.True(t, st.FastBurn)
+	assert.Equal(t, int64(120), st.Runs)
+	assert.Equal(t, int64(20), st.Failures)
+	assert.InDelta(t, 100.0/120, st.Compliance, 1e-9)
+	assert.InDelta(t, 1-(20.0/120)/0.01, st.BudgetRemaining, 1e-9)
+	// 20 failures and the 59 passes of the last hour
+	assert.InDelta(t, (20.0/79)/0.01, st.BurnRates["1h"], 1e-9)
+
+	// Old runs fall out of the short windows
+	later := now.Add(2 * time.Hour)
+	st = ss.current(later)
+	assert.Equal(t, 0.0, st.BurnRates["1h"])
+	assert.False(t, st.FastBurn)
+	assert.Equal(t, int64(120), st.Runs)
+
+	// And the whole window
+	st = ss.current(now.Add(25 * time.Hour))
+	assert.Zero(t, st.Runs)
+	assert.Equal(t, 1.0, st.Compliance)
+
+	// Once the fast burn is over, the next one notifies again
+	_, burning := ss.record(later, false)
+	assert.False(t, burning)
+	_, burning = ss.record(later, true)
+	assert.True(t, burning)
+}
+
+func TestSLOStateLongWindow(t *testing.T) {
+	ss := newSLOState(SLO{Target: 0.999, Window: 30 * 24 * time.Hour})
+	assert.Len(t, ss.window.buckets, sloMaxBuckets)
+	assert.Equal(t, time.Hour, ss.window.size)
+	assert.Len(t, ss.fine.buckets, 360)
+
+	// A run every hour for 30 days, failing on the first day
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return start.Add(time.Duration(h) * time.Hour) }
+	for h := 0; h < 30*24; h++ {
+		ss.record(at(h), h < 24)
+	}
+	st := ss.current(at(30*24 - 1))
+	assert.Equal(t, int64(30*24), st.Runs)
+	assert.Equal(t, int64(24), st.Failures)
+	assert.InDelta(t, 1-24.0/720, st.Compliance, 1e-9)
+
+	// Ten more days of passes push the failures out of the window
+	for h := 30 * 24; h < 40*24; h++ {
+		ss.record(at(h), false)
+	}
+	st = ss.current(at(40*24 - 1))
+	assert.Equal(t, int64(30*24), st.Runs)
+	assert.Zero(t, st.Failures)
+	assert.Equal(t, 1.0, st.Compliance)
+}
+
+func TestServiceSLO(t *testing.T) {
+	h := &statstest.Handler{}
+	var alerts []Alert
+	fail := false
+	s := New(
+		WithStats(stats.NewEngine("", h)),
+		WithNotifier(NotifierFunc(func(ctx context.Context, a Alert) error {
+			alerts = append(alerts, a)
+			return nil
+		})),
+		WithAlertThresholds(100, 1),
+		WithOutput(ioutil.Discard),
+	)
+	tc := TestCase{
+		Name:   "smoke",
+		Period: time.Hour,
+		SLO:    &SLO{Target: 0.999, Window: 30 * 24 * time.Hour},
+		Func: func(ctx context.Context, o *O) {
+			if fail {
+				o.Error("down")
+			}
+		},
+	}
+	run := startCase(t, s, tc)
+	defer s.Close()
+
+	run()
+	assert.Empty(t, alerts)
+	fail = true
+	run()
+	run()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StatusBudgetBurn, alerts[0].Status)
+	assert.Equal(t, "budget_burn", alerts[0].Status.String())
+	require.NotNil(t, alerts[0].SLO)
+	assert.True(t, alerts[0].SLO.FastBurn)
+
+	st := s.Status()
+	require.NotNil(t, st[0].SLO)
+	assert.Equal(t, int64(3), st[0].SLO.Runs)
+	assert.InDelta(t, 1.0/3, st[0].SLO.Compliance, 1e-9)
+
+	gauges := measures(h, "slo.compliance")
+	require.Len(t, gauges, 3)
+	assert.Equal(t, "smoke", tagValue(gauges[2].Tags, "case"))
+	var windows []string
+	for _, m := range measures(h, "slo.burn_rate") {
+		windows = append(windows, tagValue(m.Tags, "window"))
+	}
+	assert.Equal(t, []string{"5m", "1h", "6h", "5m", "1h", "6h", "5m", "1h", "6h"}, windows)
+
+	assert.Error(t, s.Add(TestCase{Name: "bad", Period: time.Hour, Func: tc.Func, SLO: &SLO{Target: 1, Window: time.Hour}}))
+	assert.Error(t, s.Add(TestCase{Name: "bad", Period: time.Hour, Func: tc.Func, SLO: &SLO{Target: 0.9}}))
+}
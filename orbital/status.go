@@ -0,0 +1,106 @@
+package orbital
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/segmentio/stats"
+)
+
+// CaseStatus is a snapshot of the state of a TestCase.
+type CaseStatus struct {
+	Name     string      `json:"name"`
+	Tags     []stats.Tag `json:"tags,omitempty"`
+	Disabled bool        `json:"disabled,omitempty"`
+	// Alert is the alerting state of the TestCase, see AlertStatus.
+	Alert string `json:"alert"`
+	// Last is the most recent Result, if the TestCase has run.
+	Last *Result `json:"last,omitempty"`
+	// SLO is set if the TestCase has an SLO.
+	SLO *SLOStatus `json:"slo,omitempty"`
+}
+
+// Status returns a snapshot of every registered TestCase, sorted by name.
+func (s *Service) Status() []CaseStatus {
+	s.mu.Lock()
+	tests := s.scheduled()
+	cases := make([]*caseState, len(tests))
+	for i, tc := range tests {
+		cases[i] = s.cases[tc.Name]
+	}
+	s.mu.Unlock()
+
+	now := s.clock.Now()
+	out := make([]CaseStatus, len(tests))
+	for i, tc := range tests {
+		cs := CaseStatus{
+			Name:     tc.Name,
+			Tags:     tc.Tags,
+			Disabled: tc.Disabled,
+			Alert:    cases[i].alert.current().String(),
+		}
+		if last, err := s.results.Results(tc.Name, 1); err == nil && len(last) > 0 {
+			cs.Last = &last[0]
+		}
+		if cases[i].slo != nil {
+			st := cases[i].slo.current(now)
+			cs.SLO = &st
+		}
+		out[i] = cs
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// scheduled returns the TestCases as currently scheduled, with any config
+// applied.  s.mu must be held.
+func (s *Service) scheduled() []TestCase {
+	if !s.started {
+		return append([]TestCase(nil), s.tests...)
+	}
+	tests := make([]TestCase, 0, len(s.runners))
+	for _, r := range s.runners {
+		tests = append(tests, r.tc)
+	}
+	return tests
+}
+
+// ServeHTTP serves Status and LoadStatus as JSON, so that a Service can be
+// mounted as the status API of the process running it.  With a "case" query
+// parameter, it serves the History of that TestCase instead, limited to the
+// most recent "n" Results if given.
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	if name := q.Get("case"); name != "" {
+		n, err := strconv.Atoi(q.Get("n"))
+		if q.Get("n") != "" && err != nil {
+			http.Error(w, "invalid n", http.StatusBadRequest)
+			return
+		}
+		results, err := s.History(name, n)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(struct {
+			Results []Result `json:"results"`
+		}{results})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(struct {
+		Cases []CaseStatus `json:"cases"`
+		Load  []LoadStatus `json:"load,omitempty"`
+	}{s.Status(), s.LoadStatus()})
+}
@@ -0,0 +1,94 @@
+package orbital
+
+import (
+	"context"
+	"time"
+
+	"github.com/segmentio/stats"
+)
+
+// StepResult is the outcome of a single O.Step.
+type StepResult struct {
+	Name     string        `json:"name"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Failed   bool          `json:"failed"`
+	// Error is the error returned by the step function, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// Step runs fn as a named phase of the test.  The phase is timed and
+// observed in the "step" histogram tagged with the case and step names, and
+// its boundaries are logged.  Metrics recorded through O while fn runs are
+// tagged with the step name.
+//
+// Each step is a span, a child of the span of the run, and the context given
+// to fn carries it.
+//
+// The step fails if fn returns an error, which is reported with Errorf, or if
+// the test fails while fn runs.  The first failed step is recorded as
+// Result.FailedStep.  Step returns the error from fn so that the test can
+// stop early.
+func (o *O) Step(name string, fn func(ctx context.Context) error) (err error) {
+	o.Logf("=== STEP %s", name)
+	o.mu.Lock()
+	prev, prevCtx := o.step, o.ctx
+	o.step = name
+	failsBefore := o.fails
+	ctx, span := startSpan(o.ctx, name)
+	o.ctx = ctx
+	o.mu.Unlock()
+
+	start := o.timeSource().Now()
+	// Deferred so that the step is still recorded if fn calls FailNow
+	defer func() {
+		o.endStep(name, prev, prevCtx, start, failsBefore, err, span)
+	}()
+	err = fn(ctx)
+	if err != nil {
+		o.Errorf("step %s: %v", name, err)
+	}
+	return err
+}
+
+// endStep records the result of the step name and restores the step and
+// context which were in progress before it.
+func (o *O) endStep(name, prev string, prevCtx context.Context, start time.Time, failsBefore int, err error, span *Span) {
+	dur := o.timeSource().Now().Sub(start)
+	o.mu.Lock()
+	o.step, o.ctx = prev, prevCtx
+	failed := err != nil || o.fails > failsBefore
+	sr := StepResult{
+		Name:     name,
+		Start:    start,
+		Duration: dur,
+		Failed:   failed,
+	}
+	if err != nil {
+		sr.Error = err.Error()
+	}
+	o.steps = append(o.steps, sr)
+	if failed && o.failedStep == "" {
+		o.failedStep = name
+	}
+	o.mu.Unlock()
+
+	result := "pass"
+	if failed {
+		result = "fail"
+	}
+	tags := append([]stats.Tag{
+		stats.T("step", name),
+		stats.T("result", result),
+	}, o.tags...)
+	o.stats.Observe("step", dur, tags...)
+	if err != nil {
+		span.Attributes = map[string]string{"error": err.Error()}
+	}
+	endSpan(o.exporter, span, failed)
+	if failed {
+		o.Logf("--- STEP FAIL: %s (%s)", name, dur)
+	} else {
+		o.Logf("--- STEP PASS: %s (%s)", name, dur)
+	}
+}
@@ -0,0 +1,85 @@
+package orbital
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/segmentio/stats"
+	"github.com/segmentio/stats/statstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStep(t *testing.T) {
+	h := &statstest.Handler{}
+	var out bytes.Buffer
+	s := New(WithStats(stats.NewEngine("", h)), WithOutput(&out))
+	run := startCase(t, s, TestCase{
+		Name:   "step_test",
+		Period: time.Hour,
+		Func: func(ctx context.Context, o *O) {
+			o.Step("send", func(ctx context.Context) error {
+				o.Incr("sent")
+				return nil
+			})
+			if err := o.Step("wait", func(ctx context.Context) error {
+				return errors.New("webhook never called")
+			}); err != nil {
+				return
+			}
+			o.Step("verify", func(ctx context.Context) error { return nil })
+		},
+	})
+	defer s.Close()
+
+	last := run()
+	assert.True(t, last.Failed)
+	assert.Equal(t, "wait", last.FailedStep)
+	require.Len(t, last.Steps, 2)
+	assert.False(t, last.Steps[0].Failed)
+	assert.True(t, last.Steps[1].Failed)
+	assert.Equal(t, "webhook never called", last.Steps[1].Error)
+	assert.Equal(t, "send", tagValue(last.Metrics[0].Tags, "step"))
+
+	steps := measures(h, "step")
+	require.Len(t, steps, 2)
+	assert.Equal(t, "send", tagValue(steps[0].Tags, "step"))
+	assert.Equal(t, "pass", tagValue(steps[0].Tags, "result"))
+	assert.Equal(t, "wait", tagValue(steps[1].Tags, "step"))
+	assert.Equal(t, "fail", tagValue(steps[1].Tags, "result"))
+	assert.Equal(t, "step_test", tagValue(steps[1].Tags, "case"))
+
+	assert.Contains(t, out.String(), "=== STEP send\n")
+	assert.Contains(t, out.String(), "--- STEP FAIL: wait (")
+	assert.NotContains(t, out.String(), "verify")
+}
+
+func TestStepFailsAfterFailedStep(t *testing.T) {
+	s := New(WithOutput(ioutil.Discard))
+	run := startCase(t, s, TestCase{
+		Name:   "steps_test",
+		Period: time.Hour,
+		Func: func(ctx context.Context, o *O) {
+			o.Step("first", func(ctx context.Context) error {
+				return errors.New("first failed")
+			})
+			o.Step("second", func(ctx context.Context) error { return nil })
+			o.Step("third", func(ctx context.Context) error {
+				o.Fail()
+				return nil
+			})
+		},
+	})
+	defer s.Close()
+
+	r := run()
+	assert.Equal(t, "first", r.FailedStep)
+	require.Len(t, r.Steps, 3)
+	assert.True(t, r.Steps[0].Failed)
+	assert.False(t, r.Steps[1].Failed)
+	assert.True(t, r.Steps[2].Failed, "a step failing after an earlier one should be failed")
+}
@@ -0,0 +1,157 @@
+package orbital
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/segmentio/stats"
+)
+
+// ResultStore keeps the history of Results.  The Service appends every
+// Result to it, serves the status API from it, and replays it on Run to
+// restore the alert state of each TestCase.
+type ResultStore interface {
+	// Append stores r.
+	Append(r Result) error
+	// Results returns the most recent n Results of the named TestCase,
+	// oldest first.  If n is zero or negative, every stored Result is
+	// returned.
+	Results(name string, n int) ([]Result, error)
+	Close() error
+}
+
+// WithResultStore makes the Service keep its Results in rs.  By default they
+// are kept in a MemoryStore of 100 Results per TestCase.  The Service closes
+// rs when it is closed.
+func WithResultStore(rs ResultStore) func(*Service) {
+	return func(svc *Service) {
+		svc.results = rs
+	}
+}
+
+// MemoryStore is a ResultStore which keeps a bounded number of Results per
+// TestCase in memory.
+type MemoryStore struct {
+	size int
+
+	mu    sync.Mutex
+	rings map[string]*ring
+}
+
+var _ ResultStore = (*MemoryStore)(nil)
+
+// NewMemoryStore returns a MemoryStore keeping the most recent size Results
+// of each TestCase.
+func NewMemoryStore(size int) *MemoryStore {
+	if size < 1 {
+		size = 1
+	}
+	return &MemoryStore{size: size, rings: make(map[string]*ring)}
+}
+
+// Append stores r, dropping the oldest Result of the TestCase if it is full.
+func (m *MemoryStore) Append(r Result) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rg := m.rings[r.Name]
+	if rg == nil {
+		rg = &ring{buf: make([]Result, m.size)}
+		m.rings[r.Name] = rg
+	}
+	rg.push(r)
+	return nil
+}
+
+// Results returns the most recent n Results of the named TestCase, oldest
+// first.
+func (m *MemoryStore) Results(name string, n int) ([]Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rg := m.rings[name]
+	if rg == nil {
+		return nil, nil
+	}
+	return rg.last(n), nil
+}
+
+// Close does nothing.
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// ring is a fixed size circular buffer of Results.
+type ring struct {
+	buf  []Result
+	next int
+	full bool
+}
+
+func (rg *ring) push(r Result) {
+	rg.buf[rg.next] = r
+	rg.next = (rg.next + 1) % len(rg.buf)
+	if rg.next == 0 {
+		rg.full = true
+	}
+}
+
+func (rg *ring) len() int {
+	if rg.full {
+		return len(rg.buf)
+	}
+	return rg.next
+}
+
+// last returns copies of the most recent n Results, oldest first.
+func (rg *ring) last(n int) []Result {
+	size := rg.len()
+	if n <= 0 || n > size {
+		n = size
+	}
+	out := make([]Result, n)
+	for i := range out {
+		out[i] = rg.buf[(rg.next-n+i+len(rg.buf))%len(rg.buf)]
+	}
+	return out
+}
+
+// store appends r to the ResultStore, reporting any error.
+func (s *Service) store(r Result) {
+	if err := s.results.Append(r); err != nil {
+		s.stats.Incr("store.error", stats.T("case", r.Name))
+		fmt.Fprintf(s.w, "store %s: %v\n", r.Name, err)
+	}
+}
+
+// replay restores the alert and SLO state of tests from the ResultStore,
+// without notifying.  s.mu must be held.
+func (s *Service) replay(tests []TestCase) {
+	for _, tc := range tests {
+		cs := s.cases[tc.Name]
+		if cs == nil {
+			continue
+		}
+		results, err := s.results.Results(tc.Name, 0)
+		if err != nil {
+			s.stats.Incr("store.error", stats.T("case", tc.Name))
+			fmt.Fprintf(s.w, "replay %s: %v\n", tc.Name, err)
+			continue
+		}
+		failAfter, recoverAfter := s.thresholds(tc)
+		for _, r := range results {
+			cs.setLast(r)
+			if r.Skipped {
+				continue
+			}
+			cs.alert.record(tc, r, failAfter, recoverAfter)
+			if cs.slo != nil {
+				cs.slo.record(r.Start.Add(r.Duration), r.Failed)
+			}
+		}
+	}
+}
+
+// History returns the most recent n Results of the named TestCase, oldest
+// first.  If n is zero or negative, every stored Result is returned.
+func (s *Service) History(name string, n int) ([]Result, error) {
+	return s.results.Results(name, n)
+}
@@ -0,0 +1,252 @@
+package orbital
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FileStoreConfig configures a FileStore.
+type FileStoreConfig struct {
+	// MaxResults is the number of Results kept per TestCase.  Defaults to
+	// 1000.
+	MaxResults int
+	// Retention is how long Results are kept.  If zero, Results are only
+	// dropped to stay within MaxResults.
+	Retention time.Duration
+	// Clock is what Retention is measured on.  Defaults to SystemClock; a
+	// Service using WithClock should be given a FileStore using the same.
+	Clock TimeSource
+}
+
+// compactSlack is the number of dropped Results a FileStore tolerates in its
+// file before compacting it, on top of the Results it keeps.
+const compactSlack = 1000
+
+// FileStore is a ResultStore which appends Results to a file as JSON lines.
+// The Results it keeps are also held in memory, so reads never touch the
+// file.  Once the file holds more than twice as many Results as are kept, it
+// is compacted by rewriting it with only the kept Results.
+type FileStore struct {
+	path string
+	c    FileStoreConfig
+
+	mu sync.Mutex
+	f  *os.File
+	// set when a compaction couldn't reopen the file, so that the next
+	// Append retries
+	reopen bool
+	// opens the file for appending; replaced by tests
+	open    func(path string) (*os.File, error)
+	results map[string][]Result
+	// number of Results in the file, and number of those which are kept
+	lines, kept int
+}
+
+var _ ResultStore = (*FileStore)(nil)
+
+// NewFileStore opens the FileStore at path, creating it if needed.  Existing
+// Results are loaded and the file compacted.  Lines which can't be decoded,
+// such as a torn final write, are dropped.
+func NewFileStore(path string, c FileStoreConfig) (*FileStore, error) {
+	if c.MaxResults <= 0 {
+		c.MaxResults = 1000
+	}
+	if c.Clock == nil {
+		c.Clock = SystemClock
+	}
+	fs := &FileStore{
+		path:    path,
+		c:       c,
+		open:    openAppend,
+		results: make(map[string][]Result),
+	}
+	if err := fs.load(); err != nil {
+		return nil, err
+	}
+	if err := fs.compact(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+func (fs *FileStore) load() error {
+	f, err := os.Open(fs.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "opening result store")
+	}
+	defer f.Close()
+	rd := bufio.NewReader(f)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var r Result
+			if json.Unmarshal(line, &r) == nil {
+				fs.lines++
+				fs.keep(r)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "reading result store")
+		}
+	}
+}
+
+// keep adds r to the kept Results, dropping any which are too old or too
+// many.  fs.mu must be held, or fs not yet shared.
+func (fs *FileStore) keep(r Result) {
+	rs := append(fs.results[r.Name], r)
+	fs.kept++
+	drop := 0
+	if len(rs) > fs.c.MaxResults {
+		drop = len(rs) - fs.c.MaxResults
+	}
+	if fs.c.Retention > 0 {
+		cutoff := fs.c.Clock.Now().Add(-fs.c.Retention)
+		for drop < len(rs) && rs[drop].Start.Before(cutoff) {
+			drop++
+		}
+	}
+	fs.kept -= drop
+	fs.results[r.Name] = append([]Result(nil), rs[drop:]...)
+}
+
+// Append writes r to the file, compacting it if needed.  If an earlier
+// compaction couldn't reopen the file, Append tries again first.
+func (fs *FileStore) Append(r Result) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return errors.Wrap(err, "encoding result")
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.f == nil {
+		if !fs.reopen {
+			return errors.New("result store is closed")
+		}
+		if err := fs.openFile(); err != nil {
+			return err
+		}
+	}
+	if _, err := fs.f.Write(append(b, '\n')); err != nil {
+		return errors.Wrap(err, "writing result store")
+	}
+	fs.lines++
+	fs.keep(r)
+	if fs.lines > 2*fs.kept+compactSlack {
+		return fs.compact()
+	}
+	return nil
+}
+
+// Results returns the most recent n Results of the named TestCase, oldest
+// first.
+func (fs *FileStore) Results(name string, n int) ([]Result, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	rs := fs.results[name]
+	if fs.c.Retention > 0 {
+		cutoff := fs.c.Clock.Now().Add(-fs.c.Retention)
+		for len(rs) > 0 && rs[0].Start.Before(cutoff) {
+			rs = rs[1:]
+		}
+	}
+	if n > 0 && n < len(rs) {
+		rs = rs[len(rs)-n:]
+	}
+	return append([]Result(nil), rs...), nil
+}
+
+// Compact rewrites the file with only the kept Results.
+func (fs *FileStore) Compact() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.f == nil {
+		return errors.New("result store is closed")
+	}
+	return fs.compact()
+}
+
+// compact writes the kept Results to a temporary file, which replaces the
+// store, and reopens it for appending.  fs.mu must be held, or fs not yet
+// shared.
+func (fs *FileStore) compact() error {
+	tmp := fs.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "compacting result store")
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	n := 0
+	for _, rs := range fs.results {
+		for _, r := range rs {
+			if err := enc.Encode(r); err != nil {
+				f.Close()
+				return errors.Wrap(err, "compacting result store")
+			}
+			n++
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return errors.Wrap(err, "compacting result store")
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return errors.Wrap(err, "compacting result store")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "compacting result store")
+	}
+	if err := os.Rename(tmp, fs.path); err != nil {
+		return errors.Wrap(err, "compacting result store")
+	}
+	fs.lines, fs.kept = n, n
+
+	// The old file is gone, so its handle is of no further use
+	if fs.f != nil {
+		fs.f.Close()
+	}
+	fs.f, fs.reopen = nil, true
+	return fs.openFile()
+}
+
+// openFile opens the file for appending after a compaction.
+func (fs *FileStore) openFile() error {
+	f, err := fs.open(fs.path)
+	if err != nil {
+		return errors.Wrap(err, "reopening result store")
+	}
+	fs.f, fs.reopen = f, false
+	return nil
+}
+
+func openAppend(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+}
+
+// Close closes the file.  Further Appends fail.
+func (fs *FileStore) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.reopen = false
+	if fs.f == nil {
+		return nil
+	}
+	err := fs.f.Close()
+	fs.f = nil
+	return err
+}
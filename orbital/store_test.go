@@ -0,0 +1,188 @@
+package orbital
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ids(rs []Result) []string {
+	out := make([]string, len(rs))
+	for i, r := range rs {
+		out[i] = r.ID
+	}
+	return out
+}
+
+func TestMemoryStore(t *testing.T) {
+	m := NewMemoryStore(3)
+	for _, id := range []string{"1", "2", "3", "4"} {
+		require.NoError(t, m.Append(Result{ID: id, Name: "smoke"}))
+	}
+	require.NoError(t, m.Append(Result{ID: "x", Name: "other"}))
+
+	rs, err := m.Results("smoke", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2", "3", "4"}, ids(rs))
+	rs, _ = m.Results("smoke", 2)
+	assert.Equal(t, []string{"3", "4"}, ids(rs))
+	rs, _ = m.Results("other", 5)
+	assert.Equal(t, []string{"x"}, ids(rs))
+	rs, _ = m.Results("missing", 0)
+	assert.Empty(t, rs)
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orbital")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "results.jsonl")
+
+	fs, err := NewFileStore(path, FileStoreConfig{MaxResults: 2, Retention: time.Hour})
+	require.NoError(t, err)
+	now := time.Now()
+	require.NoError(t, fs.Append(Result{ID: "old", Name: "smoke", Start: now.Add(-2 * time.Hour)}))
+	for _, id := range []string{"1", "2", "3"} {
+		require.NoError(t, fs.Append(Result{ID: id, Name: "smoke", Start: now}))
+	}
+	require.NoError(t, fs.Append(Result{ID: "x", Name: "other", Start: now}))
+	rs, err := fs.Results("smoke", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2", "3"}, ids(rs))
+	require.NoError(t, fs.Close())
+	assert.Error(t, fs.Append(Result{ID: "4", Name: "smoke", Start: now}))
+
+	// A torn final write is dropped on load
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	f.WriteString(`{"id":"torn","na`)
+	f.Close()
+
+	fs, err = NewFileStore(path, FileStoreConfig{MaxResults: 2, Retention: time.Hour})
+	require.NoError(t, err)
+	defer fs.Close()
+	rs, _ = fs.Results("smoke", 0)
+	assert.Equal(t, []string{"2", "3"}, ids(rs))
+	rs, _ = fs.Results("other", 0)
+	assert.Equal(t, []string{"x"}, ids(rs))
+
+	// Loading compacted the file down to the kept Results
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(b), "\n"))
+
+	for i := 0; i < 2*compactSlack; i++ {
+		require.NoError(t, fs.Append(Result{ID: "n", Name: "smoke", Start: now}))
+	}
+	b, err = ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.Count(string(b), "\n") <= compactSlack+3)
+}
+
+func TestFileStoreReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orbital")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "results.jsonl")
+
+	fs, err := NewFileStore(path, FileStoreConfig{MaxResults: 1})
+	require.NoError(t, err)
+	defer fs.Close()
+	fs.open = func(string) (*os.File, error) { return nil, errors.New("too many open files") }
+	for err == nil {
+		err = fs.Append(Result{ID: "1", Name: "smoke"})
+	}
+	assert.EqualError(t, err, "reopening result store: too many open files")
+	assert.Error(t, fs.Append(Result{ID: "2", Name: "smoke"}))
+
+	// The next Append opens the file again
+	fs.open = openAppend
+	require.NoError(t, fs.Append(Result{ID: "3", Name: "smoke"}))
+	reloaded, err := NewFileStore(path, FileStoreConfig{MaxResults: 2})
+	require.NoError(t, err)
+	defer reloaded.Close()
+	rs, _ := reloaded.Results("smoke", 0)
+	assert.Equal(t, []string{"1", "3"}, ids(rs))
+}
+
+func TestFileStoreRetentionClock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orbital")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fc := NewFakeClock(epoch)
+	fs, err := NewFileStore(filepath.Join(dir, "results.jsonl"), FileStoreConfig{Retention: time.Hour, Clock: fc})
+	require.NoError(t, err)
+	defer fs.Close()
+	require.NoError(t, fs.Append(Result{ID: "1", Name: "smoke", Start: fc.Now()}))
+	fc.Advance(59 * time.Minute)
+	rs, _ := fs.Results("smoke", 0)
+	assert.Equal(t, []string{"1"}, ids(rs))
+	fc.Advance(2 * time.Minute)
+	rs, _ = fs.Results("smoke", 0)
+	assert.Empty(t, rs)
+}
+
+func TestReplay(t *testing.T) {
+	store := NewMemoryStore(10)
+	start := time.Now()
+	for i, failed := range []bool{false, true, true} {
+		store.Append(Result{
+			ID:     string(rune('a' + i)),
+			Name:   "smoke",
+			Start:  start,
+			Failed: failed,
+		})
+	}
+
+	var alerts []Alert
+	s := New(
+		WithResultStore(store),
+		WithAlertThresholds(2, 1),
+		WithNotifier(NotifierFunc(func(ctx context.Context, a Alert) error {
+			alerts = append(alerts, a)
+			return nil
+		})),
+	)
+	s.w = ioutil.Discard
+	require.NoError(t, s.Add(TestCase{
+		Name:   "smoke",
+		Period: time.Hour,
+		Func:   func(ctx context.Context, o *O) {},
+	}))
+	require.NoError(t, s.Run())
+	defer s.Close()
+
+	assert.Empty(t, alerts, "replay should not notify")
+	assert.Equal(t, StatusFailing, s.cases["smoke"].alert.current())
+
+	// The restored state recovers on the next pass
+	s.handle(context.Background(), s.tests[0])
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StatusRecovered, alerts[0].Status)
+	assert.Equal(t, 2, alerts[0].Failures)
+
+	rs, err := s.History("smoke", 0)
+	require.NoError(t, err)
+	assert.Len(t, rs, 4)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/?case=smoke&n=2", nil))
+	var body struct {
+		Results []Result `json:"results"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	require.Len(t, body.Results, 2)
+	assert.Equal(t, "c", body.Results[0].ID)
+	assert.Equal(t, rs[3].ID, s.Status()[0].Last.ID)
+}
@@ -0,0 +1,186 @@
+package orbital
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TraceParentHeader is the W3C trace context header.
+const TraceParentHeader = "traceparent"
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext identifies a span, and is what is propagated to other systems.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether sc has non-zero trace and span IDs.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// TraceParent formats sc as a W3C traceparent header value.
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceParent parses a W3C traceparent header value.
+func ParseTraceParent(s string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, errors.Errorf("invalid traceparent %q", s)
+	}
+	// Later versions may append fields, but version 00 has exactly four
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, errors.Errorf("invalid traceparent %q", s)
+	}
+	var version, flags [1]byte
+	if !decodeHex(version[:], parts[0]) || !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) || !decodeHex(flags[:], parts[3]) {
+		return sc, errors.Errorf("invalid traceparent %q", s)
+	}
+	if !sc.IsValid() {
+		return sc, errors.Errorf("invalid traceparent %q: zero ID", s)
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+// decodeHex decodes the lower case hex s into exactly dst.
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying sc.
+func ContextWithSpan(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+// SpanFromContext returns the SpanContext carried by ctx, if any.  The
+// context given to a TestFunc carries the span of its run, and the context
+// given to a step function the span of the step.
+func SpanFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanKey{}).(SpanContext)
+	return sc, ok
+}
+
+// Span is a finished span, as given to a SpanExporter.  Every run of a
+// TestCase is a span named after it, with a child span for every step.
+type Span struct {
+	Name    string
+	Context SpanContext
+	// Parent is the span ID of the parent span, zero for a root span.
+	Parent     SpanID
+	Start      time.Time
+	End        time.Time
+	Failed     bool
+	Attributes map[string]string
+}
+
+// SpanExporter receives finished spans.  ExportSpan is called synchronously
+// as each span ends, so it should not block.
+type SpanExporter interface {
+	ExportSpan(s Span)
+}
+
+// SpanExporterFunc adapts a function to the SpanExporter interface.
+type SpanExporterFunc func(s Span)
+
+// ExportSpan calls f(s).
+func (f SpanExporterFunc) ExportSpan(s Span) {
+	f(s)
+}
+
+// WithSpanExporter makes the Service export the spans of every run to e.
+// Spans are created, and their context propagated, without an exporter too.
+func WithSpanExporter(e SpanExporter) func(*Service) {
+	return func(svc *Service) {
+		svc.exporter = e
+	}
+}
+
+// startSpan starts a span named name, as a child of the span carried by ctx
+// if there is one, and returns it with a context carrying it.
+func startSpan(ctx context.Context, name string) (context.Context, *Span) {
+	sp := &Span{Name: name, Start: time.Now()}
+	if parent, ok := SpanFromContext(ctx); ok {
+		sp.Context.TraceID = parent.TraceID
+		sp.Context.Sampled = parent.Sampled
+		sp.Parent = parent.SpanID
+	} else {
+		rand.Read(sp.Context.TraceID[:])
+		sp.Context.Sampled = true
+	}
+	rand.Read(sp.Context.SpanID[:])
+	return ContextWithSpan(ctx, sp.Context), sp
+}
+
+// endSpan finishes sp and exports it if e is not nil.
+func endSpan(e SpanExporter, sp *Span, failed bool) {
+	sp.End = time.Now()
+	sp.Failed = failed
+	if e != nil {
+		e.ExportSpan(*sp)
+	}
+}
+
+// SpanContext returns the span of the run, or of the step in progress.
+func (o *O) SpanContext() SpanContext {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	sc, _ := SpanFromContext(o.ctx)
+	return sc
+}
+
+// TraceTransport is an http.RoundTripper which adds a traceparent header to
+// requests whose context carries a span, such as requests made with the
+// context given to a TestFunc.  That links traces in the system under test
+// to the run which caused them.
+type TraceTransport struct {
+	// Base makes the requests.  If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+}
+
+// RoundTrip adds the traceparent header to a copy of req and sends it.
+func (t *TraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if sc, ok := SpanFromContext(req.Context()); ok && sc.IsValid() {
+		req = req.Clone(req.Context())
+		req.Header.Set(TraceParentHeader, sc.TraceParent())
+	}
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,108 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		in    string
+		valid bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"zz-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4bf92f3577b34da6-00f067aa0ba902b7-01", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			sc, err := ParseTraceParent(test.in)
+			if !test.valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+			assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+			if test.in[:2] == "00" {
+				assert.Equal(t, test.in, sc.TraceParent())
+			}
+		})
+	}
+}
+
+func TestTracing(t *testing.T) {
+	var gotHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("Traceparent")
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: &TraceTransport{}}
+
+	var mu sync.Mutex
+	var spans []Span
+	s := New(WithSpanExporter(SpanExporterFunc(func(sp Span) {
+		mu.Lock()
+		spans = append(spans, sp)
+		mu.Unlock()
+	})), WithOutput(ioutil.Discard))
+	var run, step SpanContext
+	runSmoke := startCase(t, s, TestCase{
+		Name:   "smoke",
+		Period: time.Hour,
+		Func: func(ctx context.Context, o *O) {
+			run = o.SpanContext()
+			o.Step("send", func(ctx context.Context) error {
+				step = o.SpanContext()
+				req, _ := http.NewRequest("GET", srv.URL, nil)
+				resp, err := client.Do(req.WithContext(ctx))
+				if err != nil {
+					return err
+				}
+				return resp.Body.Close()
+			})
+			o.Step("check", func(ctx context.Context) error {
+				return errors.New("mismatch")
+			})
+		},
+	})
+	defer s.Close()
+	last := runSmoke()
+
+	require.True(t, run.IsValid())
+	assert.Equal(t, run.TraceID, step.TraceID)
+	assert.NotEqual(t, run.SpanID, step.SpanID)
+	assert.Equal(t, step.TraceParent(), gotHeader)
+
+	require.Len(t, spans, 3)
+	assert.Equal(t, "send", spans[0].Name)
+	assert.Equal(t, run.SpanID, spans[0].Parent)
+	assert.False(t, spans[0].Failed)
+	assert.Equal(t, "check", spans[1].Name)
+	assert.True(t, spans[1].Failed)
+	assert.Equal(t, "mismatch", spans[1].Attributes["error"])
+	assert.Equal(t, "smoke", spans[2].Name)
+	assert.Equal(t, run, spans[2].Context)
+	assert.Equal(t, SpanID{}, spans[2].Parent)
+	assert.True(t, spans[2].Failed)
+
+	assert.Equal(t, run.TraceID.String(), last.TraceID)
+	assert.Equal(t, last.ID, spans[2].Attributes["run.id"])
+}
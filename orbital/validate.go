@@ -0,0 +1,143 @@
+package orbital
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// validName matches the TestCase names accepted by Add and Validate.  Names
+// end up as metric tag values, so they are restricted to characters which
+// survive every stats backend unchanged.
+var validName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.:/-]*$`)
+
+// ValidationErrors lists every problem found with a set of TestCases.
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// validateCase checks tc on its own, without regard to other TestCases.
+func validateCase(tc TestCase) error {
+	if !validName.MatchString(tc.Name) {
+		return errors.Errorf("test case name %q must match %s", tc.Name, validName)
+	}
+	for k, vs := range tc.Params {
+		if len(vs) == 0 {
+			return errors.Errorf("%s: parameter %s has no values", tc.Name, k)
+		}
+	}
+	if tc.Func == nil {
+		return errors.Errorf("%s: Func is nil", tc.Name)
+	}
+	if tc.Schedule != "" {
+		if err := checkSchedule(tc.Schedule); err != nil {
+			return errors.Wrap(err, tc.Name)
+		}
+	} else if tc.Period <= 0 {
+		return errors.Errorf("%s: Period must be positive, got %s", tc.Name, tc.Period)
+	}
+	if tc.Timeout < 0 {
+		return errors.Errorf("%s: Timeout must not be negative, got %s", tc.Name, tc.Timeout)
+	}
+	if tc.Retries < 0 {
+		return errors.Errorf("%s: Retries must not be negative, got %d", tc.Name, tc.Retries)
+	}
+	if tc.SLO != nil {
+		if tc.SLO.Target <= 0 || tc.SLO.Target >= 1 {
+			return errors.Errorf("%s: SLO Target must be between 0 and 1, got %g", tc.Name, tc.SLO.Target)
+		}
+		if tc.SLO.Window <= 0 {
+			return errors.Errorf("%s: SLO Window must be positive, got %s", tc.Name, tc.SLO.Window)
+		}
+	}
+	return nil
+}
+
+// Add registers tc like Register, but first checks that it is valid and that
+// no TestCase with the same name has been registered.  With Params or
+// Environments, every expanded TestCase is checked.
+func (s *Service) Add(tc TestCase) error {
+	return s.addCases([]TestCase{tc}, nil)
+}
+
+// addCases checks and registers tcs as Add does, along with the harness h
+// unless it is nil.  Either all of them are registered or, if any is invalid
+// or clashes with a registered TestCase or with another of tcs, none are.
+func (s *Service) addCases(tcs []TestCase, h interface{}) error {
+	names := make(map[string]bool)
+	for _, tc := range tcs {
+		if err := validateCase(tc); err != nil {
+			return err
+		}
+		for _, t := range s.expand(tc) {
+			if err := validateCase(t); err != nil {
+				return err
+			}
+			if names[t.Name] {
+				return errors.Errorf("test case %s registered more than once", t.Name)
+			}
+			names[t.Name] = true
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tests {
+		if names[t.Name] {
+			return errors.Errorf("test case %s already registered", t.Name)
+		}
+	}
+	for _, l := range s.loads {
+		if names[l.lc.Name] {
+			return errors.Errorf("%s already registered as a load case", l.lc.Name)
+		}
+	}
+	for _, tc := range tcs {
+		s.register(tc)
+	}
+	if h != nil {
+		s.harnesses = append(s.harnesses, h)
+	}
+	return nil
+}
+
+// Validate checks every registered TestCase, returning ValidationErrors if
+// any are invalid, share a name, or have unknown or cyclic dependencies.  Run
+// refuses to start an invalid Service, so calling Validate from a unit test
+// catches mistakes before deploying.
+func (s *Service) Validate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.validate()
+}
+
+// validate is Validate with s.mu held.
+func (s *Service) validate() error {
+	var errs ValidationErrors
+	if err := validateEnvironments(s.envs); err != nil {
+		errs = append(errs, err)
+	}
+	seen := make(map[string]bool, len(s.tests))
+	for _, tc := range s.tests {
+		if err := validateCase(tc); err != nil {
+			errs = append(errs, err)
+		}
+		if seen[tc.Name] {
+			errs = append(errs, errors.Errorf("test case %s registered more than once", tc.Name))
+		}
+		seen[tc.Name] = true
+	}
+	if err := checkDependencies(s.tests); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package orbital
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdd(t *testing.T) {
+	noop := func(ctx context.Context, o *O) {}
+	tests := []struct {
+		scenario string
+		tc       TestCase
+		err      bool
+	}{
+		{scenario: "valid", tc: TestCase{Name: "api.smoke_v2", Period: time.Minute, Func: noop}},
+		{scenario: "schedule", tc: TestCase{Name: "nightly", Schedule: "0 3 * * *", Func: noop}},
+		{scenario: "zero period", tc: TestCase{Name: "a", Func: noop}, err: true},
+		{scenario: "negative period", tc: TestCase{Name: "a", Period: -time.Second, Func: noop}, err: true},
+		{scenario: "bad schedule", tc: TestCase{Name: "a", Schedule: "daily", Func: noop}, err: true},
+		{scenario: "schedule never matches", tc: TestCase{Name: "a", Schedule: "0 0 31 2 *", Func: noop}, err: true},
+		{scenario: "nil func", tc: TestCase{Name: "a", Period: time.Minute}, err: true},
+		{scenario: "empty name", tc: TestCase{Period: time.Minute, Func: noop}, err: true},
+		{scenario: "space in name", tc: TestCase{Name: "smoke test", Period: time.Minute, Func: noop}, err: true},
+		{scenario: "negative timeout", tc: TestCase{Name: "a", Period: time.Minute, Timeout: -1, Func: noop}, err: true},
+		{scenario: "negative retries", tc: TestCase{Name: "a", Period: time.Minute, Retries: -1, Func: noop}, err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			err := New().Add(test.tc)
+			if test.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	s := New()
+	require.NoError(t, s.Add(TestCase{Name: "a", Period: time.Minute, Func: noop}))
+	assert.Error(t, s.Add(TestCase{Name: "a", Period: time.Hour, Func: noop}), "duplicate names should be rejected")
+}
+
+func TestValidate(t *testing.T) {
+	noop := func(ctx context.Context, o *O) {}
+	s := New()
+	s.Register(TestCase{Name: "a", Period: time.Minute, Func: noop})
+	require.NoError(t, s.Validate())
+
+	s.Register(TestCase{Name: "a", Period: time.Minute, Func: noop})
+	s.Register(TestCase{Name: "b", Func: noop, DependsOn: []string{"c"}})
+	err := s.Validate()
+	require.Error(t, err)
+	assert.Len(t, err.(ValidationErrors), 3)
+	assert.EqualError(t, s.Run(), err.Error(), "Run should refuse to start an invalid service")
+}
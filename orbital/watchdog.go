@@ -0,0 +1,96 @@
+package orbital
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/stats"
+)
+
+// WithHangGrace sets how long past its timeout a TestFunc may keep running
+// before the run is declared hung and abandoned.  Defaults to 30 seconds.
+func WithHangGrace(d time.Duration) func(*Service) {
+	return func(svc *Service) {
+		svc.hangGrace = d
+	}
+}
+
+// call runs tc.Func under a watchdog.  A TestFunc which ignores ctx and is
+// still running hangGrace after ctx is done, whether by its timeout or by
+// the Service closing, is left behind: call fails the run, logs the stack of
+// the goroutine running it, and returns true so that scheduling and shutdown
+// are not blocked on it.
+func (s *Service) call(ctx context.Context, o *O, tc TestCase, timeout time.Duration) (hung bool) {
+	done := make(chan struct{})
+	gid := make(chan uint64, 1)
+	go func() {
+		defer close(done)
+		gid <- goroutineID()
+		s.labeled(ctx, o, func(ctx context.Context) {
+			tc.Func(ctx, o)
+		})
+	}()
+	id := <-gid
+
+	select {
+	case <-done:
+		return false
+	case <-ctx.Done():
+	}
+	wd := s.clock.NewTimer(s.hangGrace)
+	defer wd.Stop()
+	select {
+	case <-done:
+		return false
+	case <-wd.C():
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		o.Errorf("test hung: still running %s after its %s timeout, abandoning it", s.hangGrace, timeout)
+	} else {
+		o.Errorf("test hung: still running %s after it was cancelled, abandoning it", s.hangGrace)
+	}
+	o.Log(goroutineStack(id))
+	tags := append([]stats.Tag{
+		stats.T("case", tc.Name),
+	}, tc.Tags...)
+	s.stats.Incr("case.hung", tags...)
+	return true
+}
+
+// goroutineID returns the ID of the calling goroutine, as shown in stack
+// traces.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	// "goroutine 123 [running]:..."
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// goroutineStack returns the stack trace of the goroutine with the given ID.
+func goroutineStack(id uint64) string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	prefix := []byte("goroutine " + strconv.FormatUint(id, 10) + " ")
+	for _, g := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(g, prefix) {
+			return string(g)
+		}
+	}
+	return "goroutine " + strconv.FormatUint(id, 10) + " not found"
+}
@@ -0,0 +1,82 @@
+package orbital
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/segmentio/stats"
+	"github.com/segmentio/stats/statstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func blockForever(block chan struct{}) {
+	<-block
+}
+
+func TestWatchdog(t *testing.T) {
+	h := &statstest.Handler{}
+	s := New(WithStats(stats.NewEngine("", h)), WithHangGrace(20*time.Millisecond), WithOutput(ioutil.Discard))
+	block := make(chan struct{})
+	defer close(block)
+	run := startCase(t, s, TestCase{
+		Name:    "hung_test",
+		Period:  time.Hour,
+		Timeout: 20 * time.Millisecond,
+		Retries: 3,
+		Func: func(ctx context.Context, o *O) {
+			o.Log("ignoring ctx")
+			blockForever(block)
+		},
+	})
+
+	results := make(chan Result, 1)
+	go func() { results <- run() }()
+	var last Result
+	select {
+	case last = <-results:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handle blocked on a hung test")
+	}
+	assert.True(t, last.Failed)
+	assert.True(t, last.Hung)
+	assert.Equal(t, 1, last.Attempts, "hung runs should not be retried")
+	assert.Contains(t, last.Output, "orbital.blockForever")
+	assert.Len(t, measures(h, "case.hung"), 1)
+
+	assert.NoError(t, s.Close())
+}
+
+func TestWatchdogClose(t *testing.T) {
+	s := New(WithHangGrace(20*time.Millisecond), WithOutput(ioutil.Discard))
+	block := make(chan struct{})
+	defer close(block)
+	started := make(chan struct{})
+	require.NoError(t, s.Add(TestCase{
+		Name:    "hung_test",
+		Period:  10 * time.Millisecond,
+		Timeout: time.Hour,
+		Func: func(ctx context.Context, o *O) {
+			close(started)
+			blockForever(block)
+		},
+	}))
+	require.NoError(t, s.Run())
+	<-started
+
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waited on a hung test for its timeout")
+	}
+	last, _ := s.cases["hung_test"].lastResult()
+	assert.True(t, last.Hung)
+	assert.Contains(t, last.Output, "after it was cancelled")
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/segmentio/events"
+	"github.com/segmentio/orbital/orbital"
 )
 
 type Request struct {
@@ -17,9 +18,24 @@ type Request struct {
 	Header map[string][]string
 	Body   string
 
+	// TraceParent is the W3C traceparent header of the request, if any,
+	// which links it to the orbital run which sent it.  See TraceID.
+	TraceParent string
+
 	ReceivedAt time.Time
 }
 
+// TraceID returns the trace ID from the TraceParent of r, or the empty string
+// if it has none or orbital.ParseTraceParent rejects it.  It matches
+// Result.TraceID of the orbital run which sent the request.
+func (r Request) TraceID() string {
+	sc, err := orbital.ParseTraceParent(r.TraceParent)
+	if err != nil {
+		return ""
+	}
+	return sc.TraceID.String()
+}
+
 type Logger interface {
 	// TODO consider adding error
 	Record(r Request)
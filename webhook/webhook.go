@@ -4,16 +4,27 @@ import (
 	"io/ioutil"
 	"net/http"
 	"time"
+
+	"github.com/segmentio/orbital/orbital"
 )
 
 type Webhook struct {
-	log Logger
+	log    Logger
+	secret []byte
 }
 
 type Config struct {
 	Logger Logger
 }
 
+// WithSecret makes the Webhook reject requests which are not signed with
+// secret.  See Sign.
+func WithSecret(secret []byte) func(*Webhook) {
+	return func(h *Webhook) {
+		h.secret = secret
+	}
+}
+
 func New(c Config, opts ...func(*Webhook)) *Webhook {
 	ret := &Webhook{
 		log: c.Logger,
@@ -34,14 +45,20 @@ func (h *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.secret != nil && !Verify(h.secret, bs, r.Header.Get(SignatureHeader)) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
 	h.log.Record(Request{
-		RemoteAddr: r.RemoteAddr,
-		Method:     r.Method,
-		Proto:      r.Proto,
-		RawURL:     r.URL.String(),
-		Header:     cloneHeader(r.Header),
-		Body:       string(bs),
-		ReceivedAt: time.Now().UTC(),
+		RemoteAddr:  r.RemoteAddr,
+		Method:      r.Method,
+		Proto:       r.Proto,
+		RawURL:      r.URL.String(),
+		Header:      cloneHeader(r.Header),
+		Body:        string(bs),
+		TraceParent: r.Header.Get(orbital.TraceParentHeader),
+		ReceivedAt:  time.Now().UTC(),
 	})
 }
 
@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookTraceParent(t *testing.T) {
+	rc := make(chan Request, 1)
+	h := New(Config{Logger: NewChanLogger(rc)})
+
+	req := httptest.NewRequest("POST", "/hook", strings.NewReader("{}"))
+	req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, rc, 1)
+	r := <-rc
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", r.TraceParent)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", r.TraceID())
+}
+
+func TestRequestTraceID(t *testing.T) {
+	tests := []struct {
+		traceparent string
+		expected    string
+	}{
+		{"", ""},
+		{"garbage", ""},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", ""},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", ""},
+		{"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", ""},
+		{"zz-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ""},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", ""},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "4bf92f3577b34da6a3ce929d0e0e4736"},
+	}
+	for _, test := range tests {
+		t.Run(test.traceparent, func(t *testing.T) {
+			assert.Equal(t, test.expected, Request{TraceParent: test.traceparent}.TraceID())
+		})
+	}
+}